@@ -0,0 +1,246 @@
+package analysis
+
+import (
+	"gonetwatch/internal/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// dnsTimeout is how long a query waits for its response before it is
+	// no longer used to measure latency.
+	dnsTimeout = 5 * time.Second
+	// maxPendingDNS bounds the number of queries waiting for a response.
+	maxPendingDNS = 10000
+)
+
+// DNSReport summarizes the DNS activity seen so far.
+type DNSReport struct {
+	Queries      int64
+	Responses    int64
+	NXDomain     int64
+	NXDomainRate float64 // Percentage of responses that were NXDOMAIN
+	TopDomains   []DomainStat
+	Resolvers    []ResolverStat  // Slowest first
+	Clients      []DNSClientStat // Most queries first
+}
+
+// DomainStat holds stats for a single queried name.
+type DomainStat struct {
+	Name     string
+	Queries  int64
+	NXDomain int64
+	Types    []string // Query types seen, e.g. A, AAAA
+	Answers  []string // Answers of the latest response
+}
+
+// ResolverStat holds stats for a single DNS server.
+type ResolverStat struct {
+	IP         string
+	Name       string `json:",omitempty"` // Host name learned passively, see DeviceName
+	Responses  int64
+	Failures   int64 // SERVFAIL and REFUSED responses
+	AvgLatency time.Duration
+	MaxLatency time.Duration
+}
+
+// DNSClientStat holds stats for a single host sending DNS queries.
+type DNSClientStat struct {
+	IP       string
+	Name     string `json:",omitempty"` // Host name learned passively, see DeviceName
+	Queries  int64
+	NXDomain int64
+}
+
+// dnsTracker accumulates DNS activity. It is created with the first DNS packet.
+type dnsTracker struct {
+	queries   int64
+	responses int64
+	nxdomain  int64
+	domains   map[string]*domainCount
+	resolvers map[string]*resolverCount
+	clients   map[string]*DNSClientStat
+	pending   map[dnsKey]time.Time // Query times, to match responses
+}
+
+type domainCount struct {
+	queries  int64
+	nxdomain int64
+	types    map[uint16]bool
+	answers  []string
+}
+
+type resolverCount struct {
+	responses  int64
+	failures   int64
+	timed      int64 // Responses matched to their query
+	latencySum time.Duration
+	latencyMax time.Duration
+}
+
+// dnsKey identifies a query and its response.
+type dnsKey struct {
+	client, server string
+	port           int
+	id             uint16
+}
+
+func newDNSTracker() *dnsTracker {
+	return &dnsTracker{
+		domains:   make(map[string]*domainCount),
+		resolvers: make(map[string]*resolverCount),
+		clients:   make(map[string]*DNSClientStat),
+		pending:   make(map[dnsKey]time.Time),
+	}
+}
+
+// processDNS accounts a DNS query or response. Latency is measured on packet
+// time between a query and the response with the same ID and endpoints.
+// Must be called with s.mu held.
+func (s *TrafficStats) processDNS(pkt models.PacketData) {
+	msg := pkt.DNS
+	if msg == nil {
+		return
+	}
+	if s.dns == nil {
+		s.dns = newDNSTracker()
+	}
+	t := s.dns
+
+	name := strings.TrimSuffix(strings.ToLower(msg.QueryName), ".")
+	domain := t.domains[name]
+	if domain == nil && name != "" {
+		domain = &domainCount{types: make(map[uint16]bool)}
+		t.domains[name] = domain
+	}
+
+	if !msg.Response {
+		t.queries++
+		if domain != nil {
+			domain.queries++
+			domain.types[msg.QueryType] = true
+		}
+		client := t.clients[pkt.SrcIP]
+		if client == nil {
+			client = &DNSClientStat{IP: pkt.SrcIP}
+			t.clients[pkt.SrcIP] = client
+		}
+		client.Queries++
+		t.addPending(dnsKey{pkt.SrcIP, pkt.DstIP, pkt.SrcPort, msg.ID}, pkt.Timestamp)
+		return
+	}
+
+	t.responses++
+	resolver := t.resolvers[pkt.SrcIP]
+	if resolver == nil {
+		resolver = &resolverCount{}
+		t.resolvers[pkt.SrcIP] = resolver
+	}
+	resolver.responses++
+
+	switch msg.RCode {
+	case models.DNSRCodeNXDomain:
+		t.nxdomain++
+		if domain != nil {
+			domain.nxdomain++
+		}
+		if client := t.clients[pkt.DstIP]; client != nil {
+			client.NXDomain++
+		}
+	case models.DNSRCodeServFail, models.DNSRCodeRefused:
+		resolver.failures++
+	}
+	if domain != nil && len(msg.Answers) > 0 {
+		domain.answers = msg.Answers
+	}
+
+	key := dnsKey{pkt.DstIP, pkt.SrcIP, pkt.DstPort, msg.ID}
+	if sent, ok := t.pending[key]; ok {
+		delete(t.pending, key)
+		if latency := pkt.Timestamp.Sub(sent); latency >= 0 {
+			resolver.timed++
+			resolver.latencySum += latency
+			resolver.latencyMax = max(resolver.latencyMax, latency)
+		}
+	}
+}
+
+// addPending remembers when a query was sent. Queries that went unanswered
+// for longer than dnsTimeout are forgotten once too many are waiting.
+func (t *dnsTracker) addPending(key dnsKey, sent time.Time) {
+	if len(t.pending) >= maxPendingDNS {
+		for k, ts := range t.pending {
+			if sent.Sub(ts) > dnsTimeout {
+				delete(t.pending, k)
+			}
+		}
+		if len(t.pending) >= maxPendingDNS {
+			return
+		}
+	}
+	t.pending[key] = sent
+}
+
+// GetDNSReport returns the DNS activity, with up to limit entries per list.
+func (s *TrafficStats) GetDNSReport(limit int) DNSReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.dns
+	if t == nil {
+		return DNSReport{}
+	}
+
+	r := DNSReport{
+		Queries:   s.scaled(t.queries),
+		Responses: s.scaled(t.responses),
+		NXDomain:  s.scaled(t.nxdomain),
+	}
+	if t.responses > 0 {
+		r.NXDomainRate = float64(t.nxdomain) * 100 / float64(t.responses)
+	}
+
+	for name, d := range t.domains {
+		stat := DomainStat{Name: name, Queries: s.scaled(d.queries), NXDomain: s.scaled(d.nxdomain), Answers: d.answers}
+		for qtype := range d.types {
+			stat.Types = append(stat.Types, models.DNSTypeName(qtype))
+		}
+		sort.Strings(stat.Types)
+		r.TopDomains = append(r.TopDomains, stat)
+	}
+	sort.Slice(r.TopDomains, func(i, j int) bool {
+		return r.TopDomains[i].Queries > r.TopDomains[j].Queries
+	})
+
+	for ip, res := range t.resolvers {
+		stat := ResolverStat{IP: ip, Name: s.names.nameOf(ip), Responses: s.scaled(res.responses), Failures: s.scaled(res.failures), MaxLatency: res.latencyMax}
+		if res.timed > 0 {
+			stat.AvgLatency = res.latencySum / time.Duration(res.timed)
+		}
+		r.Resolvers = append(r.Resolvers, stat)
+	}
+	sort.Slice(r.Resolvers, func(i, j int) bool {
+		return r.Resolvers[i].AvgLatency > r.Resolvers[j].AvgLatency
+	})
+
+	for _, c := range t.clients {
+		r.Clients = append(r.Clients, DNSClientStat{IP: c.IP, Name: s.names.nameOf(c.IP), Queries: s.scaled(c.Queries), NXDomain: s.scaled(c.NXDomain)})
+	}
+	sort.Slice(r.Clients, func(i, j int) bool {
+		return r.Clients[i].Queries > r.Clients[j].Queries
+	})
+
+	r.TopDomains = truncate(r.TopDomains, limit)
+	r.Resolvers = truncate(r.Resolvers, limit)
+	r.Clients = truncate(r.Clients, limit)
+	return r
+}
+
+// truncate limits a list to its first n entries.
+func truncate[T any](list []T, n int) []T {
+	if len(list) > n {
+		return list[:n]
+	}
+	return list
+}
@@ -0,0 +1,39 @@
+package analysis
+
+import "fmt"
+
+var etherTypes = map[uint16]string{
+	0x0000: "LLC",
+	0x0800: "IPv4",
+	0x0806: "ARP",
+	0x0842: "WoL",
+	0x22f0: "AVTP",
+	0x22f3: "TRILL",
+	0x8035: "RARP",
+	0x809b: "AppleTalk",
+	0x8100: "802.1Q",
+	0x8137: "IPX",
+	0x8808: "Ethernet-Flow-Control",
+	0x8809: "Slow-Protocols",
+	0x86dd: "IPv6",
+	0x8847: "MPLS",
+	0x8848: "MPLS-Multicast",
+	0x8863: "PPPoE-Discovery",
+	0x8864: "PPPoE-Session",
+	0x888e: "EAPOL",
+	0x8892: "PROFINET",
+	0x88a8: "802.1ad",
+	0x88cc: "LLDP",
+	0x88e5: "MACsec",
+	0x88f7: "PTP",
+	0x8906: "FCoE",
+	0x9000: "Loopback",
+}
+
+// GetEtherTypeName returns the common name for an EtherType, or its hex value.
+func GetEtherTypeName(etherType uint16) string {
+	if name, ok := etherTypes[etherType]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", etherType)
+}
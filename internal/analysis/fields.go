@@ -0,0 +1,75 @@
+package analysis
+
+import (
+	"gonetwatch/internal/models"
+	"sort"
+)
+
+// FieldValueStat holds stats for a single value of an extra field.
+type FieldValueStat struct {
+	Value   string
+	Packets int64
+	Bytes   int64
+}
+
+// processExtra counts the values of user-requested fields.
+// A value repeated within one packet is counted once. Must be called with s.mu held.
+func (s *TrafficStats) processExtra(pkt models.PacketData) {
+	for name, values := range pkt.Extra {
+		byValue := s.fieldValues[name]
+		if byValue == nil {
+			byValue = make(map[string]*FieldValueStat)
+			s.fieldValues[name] = byValue
+		}
+
+		seen := make(map[string]bool, len(values))
+		for _, v := range values {
+			str := v.String()
+			if seen[str] {
+				continue
+			}
+			seen[str] = true
+
+			stat := byValue[str]
+			if stat == nil {
+				stat = &FieldValueStat{Value: str}
+				byValue[str] = stat
+			}
+			stat.Packets++
+			stat.Bytes += int64(pkt.Length)
+		}
+	}
+}
+
+// GetFieldNames returns the names of the extra fields seen so far.
+func (s *TrafficStats) GetFieldNames() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.fieldValues))
+	for name := range s.fieldValues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetTopFieldValues returns the top N values of an extra field by packets.
+func (s *TrafficStats) GetTopFieldValues(name string, limit int) []FieldValueStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make([]FieldValueStat, 0, len(s.fieldValues[name]))
+	for _, stat := range s.fieldValues[name] {
+		stats = append(stats, FieldValueStat{Value: stat.Value, Packets: s.scaled(stat.Packets), Bytes: s.scaled(stat.Bytes)})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Packets > stats[j].Packets
+	})
+
+	if len(stats) > limit {
+		return stats[:limit]
+	}
+	return stats
+}
@@ -0,0 +1,29 @@
+package analysis
+
+import "gonetwatch/internal/models"
+
+// endpoint is one side of a transport connection.
+type endpoint struct {
+	ip   string
+	port int
+}
+
+// flowKey identifies a transport connection regardless of direction.
+type flowKey struct {
+	proto string
+	a, b  endpoint // a sorts before b
+}
+
+// flowKeyOf returns the connection a packet belongs to. It reports false
+// for packets without IP addresses and ports.
+func flowKeyOf(pkt models.PacketData) (flowKey, bool) {
+	if pkt.SrcIP == "" || pkt.DstIP == "" || (pkt.SrcPort == 0 && pkt.DstPort == 0) {
+		return flowKey{}, false
+	}
+	a := endpoint{pkt.SrcIP, pkt.SrcPort}
+	b := endpoint{pkt.DstIP, pkt.DstPort}
+	if b.ip < a.ip || (b.ip == a.ip && b.port < a.port) {
+		a, b = b, a
+	}
+	return flowKey{proto: pkt.Protocol, a: a, b: b}, true
+}
@@ -0,0 +1,310 @@
+package analysis
+
+import (
+	"gonetwatch/internal/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// httpTimeout is how long a request waits for its response before it is
+	// no longer used to measure response time.
+	httpTimeout = 30 * time.Second
+	// maxPendingHTTP bounds the number of connections with requests waiting
+	// for a response.
+	maxPendingHTTP = 10000
+	// maxPipelinedHTTP bounds the requests waiting on a single connection.
+	maxPipelinedHTTP = 16
+	// maxHTTPEndpoints bounds the distinct endpoints tracked, since paths
+	// often embed IDs. Requests to further endpoints still count for their host.
+	maxHTTPEndpoints = 5000
+)
+
+// HTTPReport summarizes the cleartext HTTP activity seen so far.
+type HTTPReport struct {
+	Requests     int64
+	Responses    int64
+	ClientErrors int64               // 4xx responses
+	ServerErrors int64               // 5xx responses
+	ErrorRate    float64             // Percentage of responses that were 4xx or 5xx
+	StatusCodes  []HTTPStatusStat    // By code
+	Hosts        []HTTPHostStat      // Most requests first
+	Endpoints    []HTTPEndpointStat  // Slowest first
+	UserAgents   []HTTPUserAgentStat // Most requests first
+}
+
+// HTTPStatusStat counts the responses with one status code.
+type HTTPStatusStat struct {
+	Code  int
+	Count int64
+}
+
+// HTTPHostStat holds stats for the requests to one Host header value.
+// Requests without a Host header are listed under the server's IP address.
+type HTTPHostStat struct {
+	Host      string
+	Requests  int64
+	Responses int64   // Responses matched to a request
+	Errors    int64   // 4xx and 5xx responses
+	ErrorRate float64 // Percentage of responses that were errors
+	AvgTime   time.Duration
+}
+
+// HTTPEndpointStat holds stats for one method and path on a host.
+type HTTPEndpointStat struct {
+	Host     string
+	Method   string
+	Path     string
+	Requests int64
+	Errors   int64
+	AvgTime  time.Duration
+	MaxTime  time.Duration
+}
+
+// HTTPUserAgentStat counts the requests sent by one user agent.
+type HTTPUserAgentStat struct {
+	UserAgent string
+	Requests  int64
+}
+
+// httpTracker accumulates HTTP activity. It is created with the first HTTP packet.
+type httpTracker struct {
+	requests     int64
+	responses    int64
+	clientErrors int64
+	serverErrors int64
+	statusCodes  map[int]int64
+	hosts        map[string]*httpTiming
+	endpoints    map[httpEndpoint]*httpTiming
+	userAgents   map[string]int64
+	pending      map[flowKey][]httpRequest // Requests in the order sent, to match responses
+}
+
+// httpTiming accumulates the requests and matched responses of a host or endpoint.
+type httpTiming struct {
+	requests  int64
+	responses int64
+	errors    int64
+	timeSum   time.Duration
+	timeMax   time.Duration
+}
+
+type httpEndpoint struct {
+	host, method, path string
+}
+
+// httpRequest is a request waiting for its response.
+type httpRequest struct {
+	endpoint httpEndpoint
+	sent     time.Time
+}
+
+func newHTTPTracker() *httpTracker {
+	return &httpTracker{
+		statusCodes: make(map[int]int64),
+		hosts:       make(map[string]*httpTiming),
+		endpoints:   make(map[httpEndpoint]*httpTiming),
+		userAgents:  make(map[string]int64),
+		pending:     make(map[flowKey][]httpRequest),
+	}
+}
+
+// processHTTP accounts an HTTP request or response. HTTP/1.x answers requests
+// in order on a connection, so a response is matched to the oldest request
+// still waiting on its connection and timed on packet time.
+// Must be called with s.mu held.
+func (s *TrafficStats) processHTTP(pkt models.PacketData) {
+	msg := pkt.HTTP
+	if msg == nil {
+		return
+	}
+	if s.http == nil {
+		s.http = newHTTPTracker()
+	}
+	t := s.http
+
+	key, hasFlow := flowKeyOf(pkt)
+
+	if msg.Request {
+		host := strings.ToLower(msg.Host)
+		if host == "" {
+			host = pkt.DstIP
+		}
+		ep := httpEndpoint{host: host, method: msg.Method, path: msg.Path}
+
+		t.requests++
+		t.hostTiming(host).requests++
+		if timing := t.endpoint(ep); timing != nil {
+			timing.requests++
+		}
+		if msg.UserAgent != "" {
+			t.userAgents[msg.UserAgent]++
+		}
+		if hasFlow {
+			t.addPending(key, httpRequest{endpoint: ep, sent: pkt.Timestamp})
+		}
+		return
+	}
+
+	t.responses++
+	t.statusCodes[msg.StatusCode]++
+	isError := msg.StatusCode >= 400
+	switch {
+	case msg.StatusCode >= 500:
+		t.serverErrors++
+	case msg.StatusCode >= 400:
+		t.clientErrors++
+	}
+
+	// Informational responses precede the final response to the same request
+	if !hasFlow || msg.StatusCode < 200 {
+		return
+	}
+	queue := t.pending[key]
+	if len(queue) == 0 {
+		return
+	}
+	req := queue[0]
+	if len(queue) == 1 {
+		delete(t.pending, key)
+	} else {
+		t.pending[key] = queue[1:]
+	}
+
+	elapsed := pkt.Timestamp.Sub(req.sent)
+	for _, timing := range []*httpTiming{t.hosts[req.endpoint.host], t.endpoints[req.endpoint]} {
+		if timing == nil {
+			continue
+		}
+		timing.responses++
+		if isError {
+			timing.errors++
+		}
+		if elapsed >= 0 {
+			timing.timeSum += elapsed
+			timing.timeMax = max(timing.timeMax, elapsed)
+		}
+	}
+}
+
+// hostTiming returns the stats of a host, creating them if needed.
+func (t *httpTracker) hostTiming(host string) *httpTiming {
+	h := t.hosts[host]
+	if h == nil {
+		h = &httpTiming{}
+		t.hosts[host] = h
+	}
+	return h
+}
+
+// endpoint returns the stats of an endpoint, creating them if there is
+// room, or nil once maxHTTPEndpoints are tracked.
+func (t *httpTracker) endpoint(ep httpEndpoint) *httpTiming {
+	e := t.endpoints[ep]
+	if e == nil && len(t.endpoints) < maxHTTPEndpoints {
+		e = &httpTiming{}
+		t.endpoints[ep] = e
+	}
+	return e
+}
+
+// addPending queues a request on its connection. Connections whose requests
+// went unanswered for longer than httpTimeout are forgotten once too many are waiting.
+func (t *httpTracker) addPending(key flowKey, req httpRequest) {
+	queue, exists := t.pending[key]
+	if !exists && len(t.pending) >= maxPendingHTTP {
+		for k, q := range t.pending {
+			if req.sent.Sub(q[len(q)-1].sent) > httpTimeout {
+				delete(t.pending, k)
+			}
+		}
+		if len(t.pending) >= maxPendingHTTP {
+			return
+		}
+	}
+	if len(queue) >= maxPipelinedHTTP {
+		queue = queue[1:]
+	}
+	t.pending[key] = append(queue, req)
+}
+
+// GetHTTPReport returns the HTTP activity, with up to limit entries per list.
+func (s *TrafficStats) GetHTTPReport(limit int) HTTPReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.http
+	if t == nil {
+		return HTTPReport{}
+	}
+
+	r := HTTPReport{
+		Requests:     s.scaled(t.requests),
+		Responses:    s.scaled(t.responses),
+		ClientErrors: s.scaled(t.clientErrors),
+		ServerErrors: s.scaled(t.serverErrors),
+	}
+	if t.responses > 0 {
+		r.ErrorRate = float64(t.clientErrors+t.serverErrors) * 100 / float64(t.responses)
+	}
+
+	for code, n := range t.statusCodes {
+		r.StatusCodes = append(r.StatusCodes, HTTPStatusStat{Code: code, Count: s.scaled(n)})
+	}
+	sort.Slice(r.StatusCodes, func(i, j int) bool {
+		return r.StatusCodes[i].Code < r.StatusCodes[j].Code
+	})
+
+	for host, h := range t.hosts {
+		stat := HTTPHostStat{
+			Host:      host,
+			Requests:  s.scaled(h.requests),
+			Responses: s.scaled(h.responses),
+			Errors:    s.scaled(h.errors),
+			AvgTime:   h.avg(),
+		}
+		if h.responses > 0 {
+			stat.ErrorRate = float64(h.errors) * 100 / float64(h.responses)
+		}
+		r.Hosts = append(r.Hosts, stat)
+	}
+	sort.Slice(r.Hosts, func(i, j int) bool {
+		return r.Hosts[i].Requests > r.Hosts[j].Requests
+	})
+
+	for ep, e := range t.endpoints {
+		r.Endpoints = append(r.Endpoints, HTTPEndpointStat{
+			Host:     ep.host,
+			Method:   ep.method,
+			Path:     ep.path,
+			Requests: s.scaled(e.requests),
+			Errors:   s.scaled(e.errors),
+			AvgTime:  e.avg(),
+			MaxTime:  e.timeMax,
+		})
+	}
+	sort.Slice(r.Endpoints, func(i, j int) bool {
+		return r.Endpoints[i].AvgTime > r.Endpoints[j].AvgTime
+	})
+
+	for ua, n := range t.userAgents {
+		r.UserAgents = append(r.UserAgents, HTTPUserAgentStat{UserAgent: ua, Requests: s.scaled(n)})
+	}
+	sort.Slice(r.UserAgents, func(i, j int) bool {
+		return r.UserAgents[i].Requests > r.UserAgents[j].Requests
+	})
+
+	r.Hosts = truncate(r.Hosts, limit)
+	r.Endpoints = truncate(r.Endpoints, limit)
+	r.UserAgents = truncate(r.UserAgents, limit)
+	return r
+}
+
+// avg returns the mean response time of the matched responses.
+func (h *httpTiming) avg() time.Duration {
+	if h.responses == 0 {
+		return 0
+	}
+	return h.timeSum / time.Duration(h.responses)
+}
@@ -0,0 +1,282 @@
+package analysis
+
+import (
+	"gonetwatch/internal/models"
+	"sort"
+	"time"
+)
+
+const (
+	// pingTimeout is how long an echo request waits for its reply before it
+	// is no longer used to measure the RTT.
+	pingTimeout = 10 * time.Second
+	// maxPendingPings bounds the number of echo requests waiting for a reply.
+	maxPendingPings = 10000
+)
+
+// ICMPReport summarizes the ICMP and ICMPv6 messages seen so far.
+type ICMPReport struct {
+	Messages     int64
+	EchoRequests int64
+	EchoReplies  int64
+	Unreachable  int64
+	TimeExceeded int64
+	Redirects    int64
+	PacketTooBig int64          // Including ICMP Fragmentation Needed
+	Types        []ICMPTypeStat // Most messages first
+	Sources      []ICMPHostStat // Hosts sending error messages, most first
+	Destinations []ICMPHostStat // Hosts receiving error messages, most first
+	Pings        []PingStat     // Slowest first
+}
+
+// ICMPTypeStat counts the messages of one type and code.
+type ICMPTypeStat struct {
+	Name  string
+	Count int64
+}
+
+// ICMPHostStat counts the error messages sent or received by one host.
+type ICMPHostStat struct {
+	IP           string
+	Name         string `json:",omitempty"` // Host name learned passively, see DeviceName
+	Unreachable  int64
+	TimeExceeded int64
+	Redirects    int64
+	PacketTooBig int64
+	MinMTU       int `json:",omitempty"` // Smallest MTU reported by Packet Too Big messages
+}
+
+// PingStat holds the echo requests sent to one target.
+type PingStat struct {
+	Target   string
+	Name     string `json:",omitempty"` // Host name learned passively, see DeviceName
+	Requests int64
+	Replies  int64         // Replies from the target, whether or not their request was seen
+	Loss     float64       // Percentage of requests without a reply, including those still in flight
+	AvgRTT   time.Duration // RTTs are measured on replies matched to their request
+	MinRTT   time.Duration
+	MaxRTT   time.Duration
+}
+
+// icmpTracker accumulates ICMP activity. It is created with the first ICMP packet.
+type icmpTracker struct {
+	messages     int64
+	kinds        map[models.ICMPKind]int64
+	types        map[string]int64
+	sources      map[string]*ICMPHostStat
+	destinations map[string]*ICMPHostStat
+	pings        map[string]*pingCount
+	pending      map[pingKey]time.Time // Echo request times, to match replies
+}
+
+type pingCount struct {
+	requests int64
+	replies  int64
+	matched  int64 // Replies matched to their request, which the RTTs are measured on
+	rttSum   time.Duration
+	rttMin   time.Duration
+	rttMax   time.Duration
+}
+
+// pingKey identifies an echo request and its reply.
+type pingKey struct {
+	client, target string
+	id, seq        uint16
+}
+
+func newICMPTracker() *icmpTracker {
+	return &icmpTracker{
+		kinds:        make(map[models.ICMPKind]int64),
+		types:        make(map[string]int64),
+		sources:      make(map[string]*ICMPHostStat),
+		destinations: make(map[string]*ICMPHostStat),
+		pings:        make(map[string]*pingCount),
+		pending:      make(map[pingKey]time.Time),
+	}
+}
+
+// processICMP accounts an ICMP or ICMPv6 message. The ping RTT is measured on
+// packet time between an echo request and the reply with the same identifier,
+// sequence number and endpoints.
+// Must be called with s.mu held.
+func (s *TrafficStats) processICMP(pkt models.PacketData) {
+	msg := pkt.ICMP
+	if msg == nil {
+		return
+	}
+	if s.icmp == nil {
+		s.icmp = newICMPTracker()
+	}
+	t := s.icmp
+
+	kind := msg.Kind()
+	t.messages++
+	t.kinds[kind]++
+	t.types[msg.Name()]++
+
+	switch kind {
+	case models.ICMPEchoRequest:
+		ping := t.pings[pkt.DstIP]
+		if ping == nil {
+			ping = &pingCount{}
+			t.pings[pkt.DstIP] = ping
+		}
+		ping.requests++
+		t.addPending(pingKey{pkt.SrcIP, pkt.DstIP, msg.ID, msg.Seq}, pkt.Timestamp)
+
+	case models.ICMPEchoReply:
+		// Replies are counted on their own so that the loss stays meaningful
+		// when sampling rarely keeps both a request and its reply
+		ping := t.pings[pkt.SrcIP]
+		if ping == nil {
+			return
+		}
+		ping.replies++
+
+		key := pingKey{pkt.DstIP, pkt.SrcIP, msg.ID, msg.Seq}
+		sent, ok := t.pending[key]
+		if !ok {
+			return
+		}
+		delete(t.pending, key)
+		rtt := pkt.Timestamp.Sub(sent)
+		if rtt < 0 {
+			return
+		}
+		if ping.matched == 0 || rtt < ping.rttMin {
+			ping.rttMin = rtt
+		}
+		ping.matched++
+		ping.rttSum += rtt
+		ping.rttMax = max(ping.rttMax, rtt)
+
+	case models.ICMPUnreachable, models.ICMPTimeExceeded, models.ICMPRedirect, models.ICMPPacketTooBig:
+		t.hostStat(t.sources, pkt.SrcIP).count(kind, msg.MTU)
+		t.hostStat(t.destinations, pkt.DstIP).count(kind, msg.MTU)
+	}
+}
+
+// hostStat returns the error counts of a host, creating them if needed.
+func (t *icmpTracker) hostStat(hosts map[string]*ICMPHostStat, ip string) *ICMPHostStat {
+	h := hosts[ip]
+	if h == nil {
+		h = &ICMPHostStat{IP: ip}
+		hosts[ip] = h
+	}
+	return h
+}
+
+// count records an error message of the given kind.
+func (h *ICMPHostStat) count(kind models.ICMPKind, mtu int) {
+	switch kind {
+	case models.ICMPUnreachable:
+		h.Unreachable++
+	case models.ICMPTimeExceeded:
+		h.TimeExceeded++
+	case models.ICMPRedirect:
+		h.Redirects++
+	case models.ICMPPacketTooBig:
+		h.PacketTooBig++
+		if mtu > 0 && (h.MinMTU == 0 || mtu < h.MinMTU) {
+			h.MinMTU = mtu
+		}
+	}
+}
+
+// Errors returns the number of error messages counted.
+func (h ICMPHostStat) Errors() int64 {
+	return h.Unreachable + h.TimeExceeded + h.Redirects + h.PacketTooBig
+}
+
+// addPending remembers when an echo request was sent. Requests that went
+// unanswered for longer than pingTimeout are forgotten once too many are waiting.
+func (t *icmpTracker) addPending(key pingKey, sent time.Time) {
+	if len(t.pending) >= maxPendingPings {
+		for k, ts := range t.pending {
+			if sent.Sub(ts) > pingTimeout {
+				delete(t.pending, k)
+			}
+		}
+		if len(t.pending) >= maxPendingPings {
+			return
+		}
+	}
+	t.pending[key] = sent
+}
+
+// GetICMPReport returns the ICMP activity, with up to limit entries per list.
+func (s *TrafficStats) GetICMPReport(limit int) ICMPReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.icmp
+	if t == nil {
+		return ICMPReport{}
+	}
+
+	r := ICMPReport{
+		Messages:     s.scaled(t.messages),
+		EchoRequests: s.scaled(t.kinds[models.ICMPEchoRequest]),
+		EchoReplies:  s.scaled(t.kinds[models.ICMPEchoReply]),
+		Unreachable:  s.scaled(t.kinds[models.ICMPUnreachable]),
+		TimeExceeded: s.scaled(t.kinds[models.ICMPTimeExceeded]),
+		Redirects:    s.scaled(t.kinds[models.ICMPRedirect]),
+		PacketTooBig: s.scaled(t.kinds[models.ICMPPacketTooBig]),
+	}
+
+	for name, n := range t.types {
+		r.Types = append(r.Types, ICMPTypeStat{Name: name, Count: s.scaled(n)})
+	}
+	sort.Slice(r.Types, func(i, j int) bool {
+		return r.Types[i].Count > r.Types[j].Count
+	})
+
+	r.Sources = s.icmpHostStats(t.sources)
+	r.Destinations = s.icmpHostStats(t.destinations)
+
+	for target, p := range t.pings {
+		stat := PingStat{
+			Target:   target,
+			Name:     s.names.nameOf(target),
+			Requests: s.scaled(p.requests),
+			Replies:  s.scaled(p.replies),
+			Loss:     percent(max(p.requests-p.replies, 0), p.requests),
+			MinRTT:   p.rttMin,
+			MaxRTT:   p.rttMax,
+		}
+		if p.matched > 0 {
+			stat.AvgRTT = p.rttSum / time.Duration(p.matched)
+		}
+		r.Pings = append(r.Pings, stat)
+	}
+	sort.Slice(r.Pings, func(i, j int) bool {
+		return r.Pings[i].AvgRTT > r.Pings[j].AvgRTT
+	})
+
+	r.Types = truncate(r.Types, limit)
+	r.Sources = truncate(r.Sources, limit)
+	r.Destinations = truncate(r.Destinations, limit)
+	r.Pings = truncate(r.Pings, limit)
+	return r
+}
+
+// icmpHostStats returns scaled copies of error counts, most errors first.
+// Must be called with s.mu held.
+func (s *TrafficStats) icmpHostStats(hosts map[string]*ICMPHostStat) []ICMPHostStat {
+	list := make([]ICMPHostStat, 0, len(hosts))
+	for _, h := range hosts {
+		list = append(list, ICMPHostStat{
+			IP:           h.IP,
+			Name:         s.names.nameOf(h.IP),
+			Unreachable:  s.scaled(h.Unreachable),
+			TimeExceeded: s.scaled(h.TimeExceeded),
+			Redirects:    s.scaled(h.Redirects),
+			PacketTooBig: s.scaled(h.PacketTooBig),
+			MinMTU:       h.MinMTU,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Errors() > list[j].Errors()
+	})
+	return list
+}
@@ -0,0 +1,58 @@
+package analysis
+
+import (
+	"gonetwatch/internal/models"
+	"sort"
+)
+
+// TrackInterfaces enables a per-interface breakdown: every packet is also
+// accounted to a separate TrafficStats for the interface it was seen on.
+// It is meant for multi-interface captures and must be called before any
+// packet is processed.
+func (s *TrafficStats) TrackInterfaces() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.perInterface == nil {
+		s.perInterface = make(map[string]*TrafficStats)
+	}
+}
+
+// processInterface forwards a packet to its interface's stats.
+// Must be called with s.mu held.
+func (s *TrafficStats) processInterface(pkt models.PacketData) {
+	if s.perInterface == nil || pkt.Interface == "" {
+		return
+	}
+
+	child := s.perInterface[pkt.Interface]
+	if child == nil {
+		child = NewTrafficStats()
+		child.scale = s.scale
+		s.perInterface[pkt.Interface] = child
+	}
+	child.ProcessPacket(pkt)
+}
+
+// GetInterfaces returns the names of the interfaces seen so far, sorted.
+// It is empty unless TrackInterfaces was called.
+func (s *TrafficStats) GetInterfaces() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.perInterface))
+	for name := range s.perInterface {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ForInterface returns the stats of a single interface, or nil if no packet
+// was seen on it.
+func (s *TrafficStats) ForInterface(name string) *TrafficStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.perInterface[name]
+}
@@ -0,0 +1,179 @@
+package analysis
+
+import (
+	"gonetwatch/internal/models"
+	"sort"
+	"time"
+)
+
+// maxDeviceNames bounds the addresses and DHCP clients names are kept for.
+const maxDeviceNames = 10000
+
+// DeviceName is what was learned passively about the host using an address.
+// Names come from DHCP, mDNS, LLMNR and NetBIOS announcements; nothing is
+// ever looked up.
+type DeviceName struct {
+	IP       string
+	Name     string            `json:",omitempty"`
+	MAC      string            `json:",omitempty"`
+	ClientID string            `json:",omitempty"` // DHCP client identifier, hex
+	Source   models.NameSource `json:",omitempty"` // Protocol the name was last announced with
+	LastSeen time.Time
+}
+
+// nameTracker maps addresses to names and MACs. DHCP names are learned per
+// client MAC from its requests, and reach an address once an ACK binds it to
+// that MAC. It is created with the first announcement.
+type nameTracker struct {
+	byIP    map[string]*DeviceName
+	clients map[string]*dhcpClient // By MAC
+}
+
+type dhcpClient struct {
+	name     string
+	clientID string
+	ip       string // Address of the latest ACK
+}
+
+func newNameTracker() *nameTracker {
+	return &nameTracker{
+		byIP:    make(map[string]*DeviceName),
+		clients: make(map[string]*dhcpClient),
+	}
+}
+
+// processNames records the host names a packet announces.
+// Must be called with s.mu held.
+func (s *TrafficStats) processNames(pkt models.PacketData) {
+	if len(pkt.Names) == 0 {
+		return
+	}
+	if s.names == nil {
+		s.names = newNameTracker()
+	}
+
+	for _, h := range pkt.Names {
+		// mDNS, LLMNR and NetBIOS hosts announce their own address, so the
+		// frame comes from their MAC unless a tunnel carried it
+		if h.MAC == "" && h.IP == pkt.SrcIP && pkt.Tunnel == nil {
+			h.MAC = pkt.SrcMAC
+		}
+		if h.Source == models.NameDHCP {
+			s.names.dhcp(h, pkt.Timestamp)
+		} else if h.IP != "" {
+			s.names.announce(h, pkt.Timestamp)
+		}
+	}
+}
+
+// announce records a name a host announced for an address.
+func (t *nameTracker) announce(h models.HostName, ts time.Time) {
+	d := t.device(h.IP)
+	if d == nil {
+		return
+	}
+	if h.MAC != "" && h.MAC != d.MAC {
+		d.MAC, d.ClientID = h.MAC, ""
+	}
+	d.Name, d.Source, d.LastSeen = h.Name, h.Source, ts
+}
+
+// dhcp records a DHCP client's name and identifier, and the address an ACK
+// binds to it.
+func (t *nameTracker) dhcp(h models.HostName, ts time.Time) {
+	c := t.clients[h.MAC]
+	if c == nil {
+		if len(t.clients) >= maxDeviceNames {
+			return
+		}
+		c = &dhcpClient{}
+		t.clients[h.MAC] = c
+	}
+	if h.Name != "" {
+		c.name = h.Name
+	}
+	if h.ClientID != "" {
+		c.clientID = h.ClientID
+	}
+
+	ip := h.IP
+	if ip == "" {
+		// A request without an address renames the one the client was last
+		// given, unless it has been leased to another device since
+		if d := t.byIP[c.ip]; d == nil || d.MAC != h.MAC {
+			return
+		}
+		ip = c.ip
+	}
+	c.ip = ip
+
+	d := t.device(ip)
+	if d == nil {
+		return
+	}
+	if d.MAC != h.MAC {
+		*d = DeviceName{IP: ip} // The address now belongs to another device
+	}
+	d.MAC, d.ClientID, d.LastSeen = h.MAC, c.clientID, ts
+	if c.name != "" {
+		d.Name, d.Source = c.name, models.NameDHCP
+	}
+}
+
+// device returns the entry of an address, creating it if there is room.
+func (t *nameTracker) device(ip string) *DeviceName {
+	d := t.byIP[ip]
+	if d == nil && len(t.byIP) < maxDeviceNames {
+		d = &DeviceName{IP: ip}
+		t.byIP[ip] = d
+	}
+	return d
+}
+
+// nameOf returns the name learned for an address, or "".
+func (t *nameTracker) nameOf(ip string) string {
+	if t == nil {
+		return ""
+	}
+	if d := t.byIP[ip]; d != nil {
+		return d.Name
+	}
+	return ""
+}
+
+// GetDeviceNames returns what was learned about addresses, most recently
+// announced first, with up to limit entries.
+func (s *TrafficStats) GetDeviceNames(limit int) []DeviceName {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.names == nil {
+		return nil
+	}
+	devices := make([]DeviceName, 0, len(s.names.byIP))
+	for _, d := range s.names.byIP {
+		devices = append(devices, *d)
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].LastSeen.After(devices[j].LastSeen)
+	})
+	return truncate(devices, limit)
+}
+
+// GetNamesFor returns what was learned about the given addresses, by address,
+// for labelling them. Addresses nothing is known about are left out.
+func (s *TrafficStats) GetNamesFor(ips []string) map[string]DeviceName {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.names == nil {
+		return nil
+	}
+	names := make(map[string]DeviceName)
+	for _, ip := range ips {
+		if d := s.names.byIP[ip]; d != nil {
+			names[ip] = *d
+		}
+	}
+	return names
+}
@@ -0,0 +1,72 @@
+package analysis
+
+import (
+	"gonetwatch/internal/models"
+	"strings"
+)
+
+// payloadProtocols are dissectors that describe the payload format rather
+// than the protocol carrying it, so they are skipped when looking for the
+// highest layer (e.g. "tcp:http:json" counts as HTTP).
+var payloadProtocols = map[string]bool{
+	"data":              true,
+	"data-text-lines":   true,
+	"media":             true,
+	"json":              true,
+	"xml":               true,
+	"png":               true,
+	"image-jfif":        true,
+	"image-gif":         true,
+	"urlencoded-form":   true,
+	"mime_multipart":    true,
+	"ethertype":         true,
+	"_ws.malformed":     true,
+	"_ws.short":         true,
+	"_ws.unreassembled": true,
+	"_ws.expert":        true,
+}
+
+// protocolNames overrides the display name of dissector names that do not
+// read well when simply upper-cased.
+var protocolNames = map[string]string{
+	"eth":    "Ethernet",
+	"ip":     "IPv4",
+	"ipv6":   "IPv6",
+	"icmpv6": "ICMPv6",
+	"vlan":   "802.1Q",
+	"sll":    "Linux-SLL",
+	"null":   "Loopback",
+}
+
+// GetProtocolName returns the display name for a dissector name from frame.protocols.
+func GetProtocolName(dissector string) string {
+	if name, ok := protocolNames[dissector]; ok {
+		return name
+	}
+	return strings.ToUpper(dissector)
+}
+
+// HighestLayer returns the name of the highest-layer protocol of a packet.
+// Without a dissection chain it falls back to the transport-level label,
+// and for non-IP frames to the EtherType.
+func HighestLayer(pkt models.PacketData) string {
+	for i := len(pkt.Protocols) - 1; i >= 0; i-- {
+		proto := pkt.Protocols[i]
+		if payloadProtocols[proto] {
+			continue
+		}
+		// The TLS handshake inside QUIC Initial packets ("udp:quic:tls") is part of QUIC
+		if proto == "tls" && i > 0 && pkt.Protocols[i-1] == "quic" {
+			continue
+		}
+		return GetProtocolName(proto)
+	}
+
+	if pkt.Protocol != "" {
+		return pkt.Protocol
+	}
+	if pkt.Family == models.FamilyNone {
+		return GetEtherTypeName(pkt.EtherType)
+	}
+	return "Unknown"
+}
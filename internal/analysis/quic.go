@@ -0,0 +1,269 @@
+package analysis
+
+import (
+	"gonetwatch/internal/models"
+	"slices"
+	"sort"
+	"time"
+)
+
+const (
+	// maxQUICKeys bounds the connection IDs and address/port pairs remembered
+	// to recognise the packets of QUIC connections.
+	maxQUICKeys = 100000
+	// maxQUICConnKeys bounds the connection IDs and pairs remembered per connection.
+	maxQUICConnKeys = 16
+	// quicConnIdle is how long a connection may stay silent before it can be
+	// forgotten to make room for new ones.
+	quicConnIdle = 2 * time.Minute
+)
+
+// QUICReport summarizes the QUIC connections seen so far.
+type QUICReport struct {
+	Connections  int64
+	Packets      int64
+	Bytes        int64
+	Migrated     int64                 // Connections seen on more than one address/port pair
+	HTTP3        int64                 // Connections whose ClientHello offered h3
+	Versions     []QUICVersionStat     // Most connections first
+	Destinations []QUICDestinationStat // Most bytes first
+}
+
+// QUICVersionStat counts the connections using one QUIC version.
+type QUICVersionStat struct {
+	Version     string
+	Connections int64
+}
+
+// QUICDestinationStat holds the traffic of the QUIC connections to one server
+// name. Connections whose Initial could not be read are listed under the
+// server's IP address.
+type QUICDestinationStat struct {
+	ServerName  string
+	Connections int64
+	Packets     int64
+	Bytes       int64
+	HTTP3       int64
+}
+
+// quicTracker follows QUIC connections by their connection IDs, so that a
+// connection keeps its identity when the client's address or port changes.
+// The address/port pair is used for packets without a known connection ID,
+// e.g. when the client chose a zero-length ID. It is created with the first
+// QUIC packet.
+type quicTracker struct {
+	connections  int64
+	packets      int64
+	bytes        int64
+	migrated     int64
+	http3        int64
+	versions     map[uint32]int64
+	byID         map[string]*quicConn
+	byPath       map[flowKey]*quicConn
+	destinations map[string]*quicDestination
+}
+
+type quicConn struct {
+	dest     *quicDestination
+	destName string
+	named    bool // The destination is a server name rather than an IP address
+	version  uint32
+	path     flowKey // Latest address/port pair
+	paths    int     // Address/port pairs seen
+	ids      int     // Connection IDs remembered
+	packets  int64
+	bytes    int64
+	lastSeen time.Time
+}
+
+type quicDestination struct {
+	connections int64
+	packets     int64
+	bytes       int64
+	http3       int64
+}
+
+func newQUICTracker() *quicTracker {
+	return &quicTracker{
+		versions:     make(map[uint32]int64),
+		byID:         make(map[string]*quicConn),
+		byPath:       make(map[flowKey]*quicConn),
+		destinations: make(map[string]*quicDestination),
+	}
+}
+
+// processQUIC attributes a QUIC packet to its connection.
+// Must be called with s.mu held.
+func (s *TrafficStats) processQUIC(pkt models.PacketData) {
+	q := pkt.QUIC
+	if q == nil {
+		return
+	}
+	if s.quic == nil {
+		s.quic = newQUICTracker()
+	}
+	t := s.quic
+
+	key, hasPath := flowKeyOf(pkt)
+	conn := t.byID[q.DCID]
+	if conn == nil && hasPath {
+		conn = t.byPath[key]
+	}
+	if conn == nil {
+		conn = t.open(pkt)
+		if conn == nil {
+			return
+		}
+	}
+
+	t.addID(conn, q.DCID)
+	t.addID(conn, q.SCID)
+	if hasPath && key != conn.path {
+		if conn.paths++; conn.paths == 2 {
+			t.migrated++
+		}
+		conn.path = key
+		if conn.paths <= maxQUICConnKeys {
+			t.byPath[key] = conn
+		}
+	}
+
+	if conn.version == 0 && q.LongHeader && q.Version != models.QUICVersionNegotiation {
+		conn.version = q.Version
+		t.versions[q.Version]++
+	}
+	if h := pkt.TLS; h != nil && h.HandshakeType == models.TLSClientHello && !conn.named {
+		t.name(conn, h)
+	}
+
+	t.packets++
+	t.bytes += int64(pkt.Length)
+	conn.packets++
+	conn.bytes += int64(pkt.Length)
+	conn.lastSeen = pkt.Timestamp
+	conn.dest.packets++
+	conn.dest.bytes += int64(pkt.Length)
+}
+
+// open starts following a connection. Until its ClientHello is seen, it is
+// listed under the server's address: the destination of a client's Initial,
+// otherwise the side using the lower port.
+func (t *quicTracker) open(pkt models.PacketData) *quicConn {
+	if len(t.byID)+len(t.byPath) >= maxQUICKeys {
+		t.evictIdle(pkt.Timestamp)
+		if len(t.byID)+len(t.byPath) >= maxQUICKeys {
+			return nil
+		}
+	}
+
+	server := pkt.DstIP
+	q := pkt.QUIC
+	isInitial := q.LongHeader && q.PacketType == models.QUICInitial
+	if !isInitial && pkt.SrcPort != 0 && pkt.SrcPort < pkt.DstPort {
+		server = pkt.SrcIP
+	}
+
+	conn := &quicConn{lastSeen: pkt.Timestamp}
+	t.attribute(conn, server)
+	t.connections++
+	return conn
+}
+
+// name moves a connection, with the traffic counted so far, to the server
+// name of its ClientHello.
+func (t *quicTracker) name(conn *quicConn, h *models.TLSInfo) {
+	conn.named = true
+	if h.ServerName != "" {
+		old := conn.dest
+		old.connections--
+		old.packets -= conn.packets
+		old.bytes -= conn.bytes
+		if old.connections == 0 {
+			delete(t.destinations, conn.destName)
+		}
+		t.attribute(conn, h.ServerName)
+		conn.dest.packets += conn.packets
+		conn.dest.bytes += conn.bytes
+	}
+	if slices.Contains(h.ALPN, "h3") {
+		conn.dest.http3++
+		t.http3++
+	}
+}
+
+// attribute counts a connection for a destination.
+func (t *quicTracker) attribute(conn *quicConn, name string) {
+	d := t.destinations[name]
+	if d == nil {
+		d = &quicDestination{}
+		t.destinations[name] = d
+	}
+	d.connections++
+	conn.dest, conn.destName = d, name
+}
+
+// addID remembers a connection ID of a connection.
+func (t *quicTracker) addID(conn *quicConn, id string) {
+	if id == "" || t.byID[id] != nil || conn.ids >= maxQUICConnKeys {
+		return
+	}
+	conn.ids++
+	t.byID[id] = conn
+}
+
+// evictIdle forgets the connection IDs and paths of idle connections.
+func (t *quicTracker) evictIdle(now time.Time) {
+	for id, conn := range t.byID {
+		if now.Sub(conn.lastSeen) > quicConnIdle {
+			delete(t.byID, id)
+		}
+	}
+	for key, conn := range t.byPath {
+		if now.Sub(conn.lastSeen) > quicConnIdle {
+			delete(t.byPath, key)
+		}
+	}
+}
+
+// GetQUICReport returns the QUIC activity, with up to limit entries per list.
+func (s *TrafficStats) GetQUICReport(limit int) QUICReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.quic
+	if t == nil {
+		return QUICReport{}
+	}
+
+	r := QUICReport{
+		Connections: s.scaled(t.connections),
+		Packets:     s.scaled(t.packets),
+		Bytes:       s.scaled(t.bytes),
+		Migrated:    s.scaled(t.migrated),
+		HTTP3:       s.scaled(t.http3),
+	}
+
+	for v, n := range t.versions {
+		r.Versions = append(r.Versions, QUICVersionStat{Version: models.QUICVersionName(v), Connections: s.scaled(n)})
+	}
+	sort.Slice(r.Versions, func(i, j int) bool {
+		return r.Versions[i].Connections > r.Versions[j].Connections
+	})
+
+	for name, d := range t.destinations {
+		r.Destinations = append(r.Destinations, QUICDestinationStat{
+			ServerName:  name,
+			Connections: s.scaled(d.connections),
+			Packets:     s.scaled(d.packets),
+			Bytes:       s.scaled(d.bytes),
+			HTTP3:       s.scaled(d.http3),
+		})
+	}
+	sort.Slice(r.Destinations, func(i, j int) bool {
+		return r.Destinations[i].Bytes > r.Destinations[j].Bytes
+	})
+
+	r.Versions = truncate(r.Versions, limit)
+	r.Destinations = truncate(r.Destinations, limit)
+	return r
+}
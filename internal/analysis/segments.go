@@ -0,0 +1,128 @@
+package analysis
+
+import (
+	"fmt"
+	"gonetwatch/internal/models"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxSegments bounds the segments broken down separately, so that a trunk
+	// or a fabric with many networks cannot grow the breakdown without limit.
+	maxSegments = 64
+	// OtherSegments names the segment adding up the traffic of the segments
+	// seen once maxSegments were already tracked.
+	OtherSegments = "Other segments"
+)
+
+// Addressing selects which headers of tunneled packets are used to attribute traffic.
+type Addressing int
+
+const (
+	AddressOuter Addressing = iota // The tunnel endpoints
+	AddressInner                   // The hosts talking through the tunnel
+)
+
+// String returns the name of the addressing mode.
+func (a Addressing) String() string {
+	if a == AddressInner {
+		return "inner"
+	}
+	return "outer"
+}
+
+// HasTunnels reports whether any tunneled packet was seen, i.e. whether the
+// inner addressing differs from the outer one.
+func (s *TrafficStats) HasTunnels() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.tunnelInner) > 0
+}
+
+// SegmentName returns the name of the VLAN and tunnel network a packet
+// belongs to, e.g. "VLAN 100 / VXLAN 5001", or "" for untagged traffic.
+func SegmentName(pkt models.PacketData) string {
+	var parts []string
+	if len(pkt.VLANs) > 0 {
+		ids := make([]string, len(pkt.VLANs))
+		for i, id := range pkt.VLANs {
+			ids[i] = fmt.Sprint(id)
+		}
+		parts = append(parts, "VLAN "+strings.Join(ids, "."))
+	}
+	if t := pkt.Tunnel; t != nil {
+		if t.Type == models.TunnelGRE && t.VNI == 0 {
+			parts = append(parts, t.Type)
+		} else {
+			parts = append(parts, fmt.Sprintf("%s %d", t.Type, t.VNI))
+		}
+	}
+	return strings.Join(parts, " / ")
+}
+
+// TrackSegments enables a per-segment breakdown: every VLAN-tagged or
+// tunneled packet is also accounted to a separate TrafficStats for its
+// VLAN and VNI (see SegmentName). Segments only keep totals, rates, address
+// families, top talkers and protocols, not the protocol trackers. Untagged
+// traffic is only counted in the combined stats. It must be called before
+// any packet is processed.
+func (s *TrafficStats) TrackSegments() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.perSegment == nil {
+		s.perSegment = make(map[string]*TrafficStats)
+	}
+}
+
+// processSegment forwards a packet to its segment's stats.
+// Must be called with s.mu held.
+func (s *TrafficStats) processSegment(pkt models.PacketData) {
+	if s.perSegment == nil || (len(pkt.VLANs) == 0 && pkt.Tunnel == nil) {
+		return
+	}
+
+	name := SegmentName(pkt)
+	child := s.perSegment[name]
+	if child == nil && len(s.perSegment) >= maxSegments {
+		name = OtherSegments
+		child = s.perSegment[name]
+	}
+	if child == nil {
+		child = NewTrafficStats()
+		child.scale = s.scale
+		child.totalsOnly = true
+		s.perSegment[name] = child
+	}
+	child.ProcessPacket(pkt)
+}
+
+// GetSegments returns the names of the segments seen so far, sorted, with
+// OtherSegments last. It is empty unless TrackSegments was called.
+func (s *TrafficStats) GetSegments() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.perSegment))
+	for name := range s.perSegment {
+		if name != OtherSegments {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if s.perSegment[OtherSegments] != nil {
+		names = append(names, OtherSegments)
+	}
+	return names
+}
+
+// ForSegment returns the stats of a single segment, or nil if no packet
+// was seen in it.
+func (s *TrafficStats) ForSegment(name string) *TrafficStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.perSegment[name]
+}
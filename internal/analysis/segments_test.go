@@ -0,0 +1,31 @@
+package analysis
+
+import (
+	"testing"
+
+	"gonetwatch/internal/models"
+)
+
+func TestSegmentName(t *testing.T) {
+	tests := []struct {
+		name string
+		pkt  models.PacketData
+		want string
+	}{
+		{"untagged", models.PacketData{}, ""},
+		{"vlan", models.PacketData{VLANs: []uint16{100}}, "VLAN 100"},
+		{"stacked vlans", models.PacketData{VLANs: []uint16{100, 200}}, "VLAN 100.200"},
+		{"vxlan", models.PacketData{Tunnel: &models.TunnelInfo{Type: models.TunnelVXLAN, VNI: 5001}}, "VXLAN 5001"},
+		{"geneve", models.PacketData{Tunnel: &models.TunnelInfo{Type: models.TunnelGENEVE, VNI: 42}}, "GENEVE 42"},
+		{"gre without key", models.PacketData{Tunnel: &models.TunnelInfo{Type: models.TunnelGRE}}, "GRE"},
+		{"gre with key", models.PacketData{Tunnel: &models.TunnelInfo{Type: models.TunnelGRE, VNI: 7}}, "GRE 7"},
+		{"vlan and vxlan", models.PacketData{VLANs: []uint16{100}, Tunnel: &models.TunnelInfo{Type: models.TunnelVXLAN, VNI: 5001}}, "VLAN 100 / VXLAN 5001"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SegmentName(tt.pkt); got != tt.want {
+				t.Errorf("SegmentName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package analysis
 
 import (
 	"gonetwatch/internal/models"
+	"math"
 	"sort"
 	"sync"
 	"time"
@@ -10,33 +11,113 @@ import (
 // IPStat holds stats for a single IP.
 type IPStat struct {
 	IP    string
+	Name  string `json:",omitempty"` // Host name learned passively, see DeviceName
 	Bytes int
 }
 
-// ProtocolStat holds stats for a single protocol.
+// ProtocolStat holds stats for a single highest-layer protocol.
 type ProtocolStat struct {
 	Protocol string
-	Count    int64
+	Count    int64 // Packets
+	Bytes    int64
+}
+
+// FamilyStat holds stats for a single address family.
+type FamilyStat struct {
+	Family  models.AddressFamily
+	Packets int64
+	Bytes   int64
+}
+
+// LinkStat holds stats for a single link-layer protocol (EtherType).
+type LinkStat struct {
+	Name    string
+	Packets int64
+	Bytes   int64
 }
 
 // TrafficStats tracks network statistics.
+// All rates are measured on packet time (the capture timestamps), not on
+// wall-clock time, so buffered live captures and capture files report the
+// rates the traffic actually had.
 type TrafficStats struct {
 	mu             sync.Mutex
 	totalBytes     int64
+	totalPackets   int64
 	windowBytes    int64
 	windowPackets  int64
-	lastTick       time.Time
+	firstSeen      time.Time // Timestamp of the first packet
+	clock          time.Time // Latest packet timestamp seen
+	lastTick       time.Time // Packet clock at the previous GetRates call
 	ipBytes        map[string]int
+	tunnelOuter    map[string]int // Bytes of tunneled packets by outer source IP
+	tunnelInner    map[string]int // Bytes of tunneled packets by inner source IP
 	protocolCounts map[string]int64
+	protocolBytes  map[string]int64
+	familyPackets  map[models.AddressFamily]int64
+	familyBytes    map[models.AddressFamily]int64
+	linkPackets    map[string]int64
+	linkBytes      map[string]int64
+	fieldValues    map[string]map[string]*FieldValueStat
+	perInterface   map[string]*TrafficStats // nil unless TrackInterfaces was called
+	perSegment     map[string]*TrafficStats // nil unless TrackSegments was called
+	tcp            *tcpTracker              // nil until a TCP packet is seen
+	icmp           *icmpTracker             // nil until an ICMP packet is seen
+	dns            *dnsTracker              // nil until a DNS packet is seen
+	tls            *tlsTracker              // nil until a TLS handshake is seen
+	http           *httpTracker             // nil until an HTTP packet is seen
+	quic           *quicTracker             // nil until a QUIC packet is seen
+	names          *nameTracker             // nil until a host name is announced
+	scale          float64                  // Packets each processed packet stands for (see SetSampleScale)
+	totalsOnly     bool                     // Set on segment stats, which skip the protocol trackers
 }
 
 // NewTrafficStats creates a new TrafficStats instance.
 func NewTrafficStats() *TrafficStats {
 	return &TrafficStats{
-		lastTick:       time.Now(),
 		ipBytes:        make(map[string]int),
+		tunnelOuter:    make(map[string]int),
+		tunnelInner:    make(map[string]int),
 		protocolCounts: make(map[string]int64),
+		protocolBytes:  make(map[string]int64),
+		familyPackets:  make(map[models.AddressFamily]int64),
+		familyBytes:    make(map[models.AddressFamily]int64),
+		linkPackets:    make(map[string]int64),
+		linkBytes:      make(map[string]int64),
+		fieldValues:    make(map[string]map[string]*FieldValueStat),
+		scale:          1,
+	}
+}
+
+// SetSampleScale declares that each processed packet stands for scale packets,
+// because the capture is sampled. Counts stay exact internally and every
+// getter scales them up, so the results are estimates of the full traffic.
+// It must be called before any packet is processed.
+func (s *TrafficStats) SetSampleScale(scale float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if scale < 1 {
+		scale = 1
+	}
+	s.scale = scale
+}
+
+// SampleScale returns the factor counts are scaled by; 1 unless sampling.
+func (s *TrafficStats) SampleScale() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.scale
+}
+
+// scaled estimates a full-traffic count from a sampled one.
+// Must be called with s.mu held.
+func (s *TrafficStats) scaled(n int64) int64 {
+	if s.scale == 1 {
+		return n
 	}
+	return int64(math.Round(float64(n) * s.scale))
 }
 
 // ProcessPacket updates stats with a new packet.
@@ -44,38 +125,100 @@ func (s *TrafficStats) ProcessPacket(pkt models.PacketData) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.advanceClock(pkt.Timestamp)
+
 	s.totalBytes += int64(pkt.Length)
+	s.totalPackets++
 	s.windowBytes += int64(pkt.Length)
 	s.windowPackets++
 
+	// Update address family breakdown
+	s.familyPackets[pkt.Family]++
+	s.familyBytes[pkt.Family] += int64(pkt.Length)
+
+	// Update link-layer breakdown
+	link := linkName(pkt)
+	s.linkPackets[link]++
+	s.linkBytes[link] += int64(pkt.Length)
+
 	// Update Top Talkers (Source IP)
 	if pkt.SrcIP != "" {
 		s.ipBytes[pkt.SrcIP] += pkt.Length
 	}
-
-	// Update Protocol Distribution
-	// Use a default if protocol is empty (though tshark usually provides it)
-	proto := pkt.Protocol
-	if proto == "" {
-		proto = "Unknown"
+	if pkt.Tunnel != nil && pkt.SrcIP != "" && pkt.Tunnel.SrcIP != "" {
+		s.tunnelOuter[pkt.SrcIP] += pkt.Length
+		s.tunnelInner[pkt.Tunnel.SrcIP] += pkt.Length
 	}
+
+	// Update Protocol Distribution (by highest-layer protocol)
+	proto := HighestLayer(pkt)
 	s.protocolCounts[proto]++
+	s.protocolBytes[proto] += int64(pkt.Length)
+
+	// Segment stats stop here, see TrackSegments
+	if s.totalsOnly {
+		return
+	}
+
+	// Update transport and application-layer breakdowns. These describe the
+	// packet a tunnel carries, so they use its addressing.
+	inner := pkt.Inner()
+	s.processTCP(inner)
+	s.processICMP(inner)
+	s.processDNS(inner)
+	s.processTLS(inner)
+	s.processHTTP(inner)
+	s.processQUIC(inner)
+	s.processNames(inner)
+
+	// Update user-requested field values
+	s.processExtra(pkt)
+
+	// Update per-interface and per-segment breakdowns
+	s.processInterface(pkt)
+	s.processSegment(pkt)
+}
+
+// advanceClock moves the packet clock forward. Out-of-order timestamps
+// never move it backwards. Must be called with s.mu held.
+func (s *TrafficStats) advanceClock(ts time.Time) {
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	if s.firstSeen.IsZero() {
+		s.firstSeen = ts
+		s.lastTick = ts
+	}
+	if ts.After(s.clock) {
+		s.clock = ts
+	}
+}
+
+// linkName returns the link-layer protocol name of a packet. IP packets are
+// named after their address family, since raw IP links carry no EtherType.
+func linkName(pkt models.PacketData) string {
+	if pkt.Family != models.FamilyNone {
+		return pkt.Family.String()
+	}
+	return GetEtherTypeName(pkt.EtherType)
 }
 
 // GetRates returns the bandwidth (bps) and packet rate (pps) since the last call.
+// The window spans the packet time elapsed between calls; while no packet time
+// has elapsed the window keeps accumulating and zero rates are returned.
 func (s *TrafficStats) GetRates() (float64, float64) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	now := time.Now()
+	now := s.clock
 	duration := now.Sub(s.lastTick).Seconds()
-	if duration == 0 {
+	if duration <= 0 {
 		return 0, 0
 	}
 
 	// Bytes * 8 = Bits
-	bps := (float64(s.windowBytes) * 8) / duration
-	pps := float64(s.windowPackets) / duration
+	bps := (float64(s.windowBytes) * 8 * s.scale) / duration
+	pps := float64(s.windowPackets) * s.scale / duration
 
 	// Reset window
 	s.windowBytes = 0
@@ -85,6 +228,22 @@ func (s *TrafficStats) GetRates() (float64, float64) {
 	return bps, pps
 }
 
+// GetTotals returns the number of packets and bytes processed since startup.
+func (s *TrafficStats) GetTotals() (int64, int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.scaled(s.totalPackets), s.scaled(s.totalBytes)
+}
+
+// GetTimeSpan returns the timestamps of the first and the latest packet seen.
+func (s *TrafficStats) GetTimeSpan() (time.Time, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.firstSeen, s.clock
+}
+
 // GetBandwidth returns the bandwidth in bits per second since the last call.
 // Deprecated: Use GetRates instead.
 func (s *TrafficStats) GetBandwidth() float64 {
@@ -94,13 +253,32 @@ func (s *TrafficStats) GetBandwidth() float64 {
 
 // GetTopTalkers returns the top N IPs by volume.
 func (s *TrafficStats) GetTopTalkers(limit int) []IPStat {
+	return s.GetTopTalkersBy(AddressOuter, limit)
+}
+
+// GetTopTalkersBy returns the top N IPs by volume, with tunneled packets
+// attributed to their outer or inner source address.
+func (s *TrafficStats) GetTopTalkersBy(addr Addressing, limit int) []IPStat {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	ipBytes := s.ipBytes
+	if addr == AddressInner && len(s.tunnelInner) > 0 {
+		ipBytes = make(map[string]int, len(s.ipBytes))
+		for ip, bytes := range s.ipBytes {
+			if bytes -= s.tunnelOuter[ip]; bytes > 0 {
+				ipBytes[ip] = bytes
+			}
+		}
+		for ip, bytes := range s.tunnelInner {
+			ipBytes[ip] += bytes
+		}
+	}
+
 	// Convert map to slice
-	stats := make([]IPStat, 0, len(s.ipBytes))
-	for ip, bytes := range s.ipBytes {
-		stats = append(stats, IPStat{IP: ip, Bytes: bytes})
+	stats := make([]IPStat, 0, len(ipBytes))
+	for ip, bytes := range ipBytes {
+		stats = append(stats, IPStat{IP: ip, Name: s.names.nameOf(ip), Bytes: int(s.scaled(int64(bytes)))})
 	}
 
 	// Sort descending by bytes
@@ -115,14 +293,14 @@ func (s *TrafficStats) GetTopTalkers(limit int) []IPStat {
 	return stats
 }
 
-// GetProtocolStats returns the protocol distribution.
+// GetProtocolStats returns the highest-layer protocol distribution, sorted by packets.
 func (s *TrafficStats) GetProtocolStats() []ProtocolStat {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	stats := make([]ProtocolStat, 0, len(s.protocolCounts))
 	for proto, count := range s.protocolCounts {
-		stats = append(stats, ProtocolStat{Protocol: proto, Count: count})
+		stats = append(stats, ProtocolStat{Protocol: proto, Count: s.scaled(count), Bytes: s.scaled(s.protocolBytes[proto])})
 	}
 
 	// Sort descending by count
@@ -132,3 +310,37 @@ func (s *TrafficStats) GetProtocolStats() []ProtocolStat {
 
 	return stats
 }
+
+// GetFamilyStats returns traffic per address family, ordered by family.
+func (s *TrafficStats) GetFamilyStats() []FamilyStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make([]FamilyStat, 0, len(s.familyPackets))
+	for family, packets := range s.familyPackets {
+		stats = append(stats, FamilyStat{Family: family, Packets: s.scaled(packets), Bytes: s.scaled(s.familyBytes[family])})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Family < stats[j].Family
+	})
+
+	return stats
+}
+
+// GetLinkStats returns the link-layer protocol distribution, sorted by packets.
+func (s *TrafficStats) GetLinkStats() []LinkStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make([]LinkStat, 0, len(s.linkPackets))
+	for name, packets := range s.linkPackets {
+		stats = append(stats, LinkStat{Name: name, Packets: s.scaled(packets), Bytes: s.scaled(s.linkBytes[name])})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Packets > stats[j].Packets
+	})
+
+	return stats
+}
@@ -0,0 +1,328 @@
+package analysis
+
+import (
+	"gonetwatch/internal/models"
+	"sort"
+	"time"
+)
+
+const (
+	// synTimeout is how long a SYN waits for its SYN-ACK before it is no
+	// longer used to measure the handshake RTT.
+	synTimeout = 30 * time.Second
+	// maxPendingSYN bounds the number of handshakes waiting for a SYN-ACK.
+	maxPendingSYN = 10000
+)
+
+// TCPReport summarizes the health of the TCP connections seen so far.
+type TCPReport struct {
+	Segments           int64
+	Retransmissions    int64
+	RetransmissionRate float64 // Percentage of segments that were retransmissions
+	DuplicateAcks      int64
+	ZeroWindows        int64
+	Resets             int64
+	Refused            int64 // Connections answered with a reset instead of a SYN-ACK
+	Handshakes         int64 // SYNs answered by a SYN-ACK
+	AvgHandshakeRTT    time.Duration
+	Hosts              []TCPHostStat    // Most problems first
+	Services           []TCPServiceStat // Slowest handshake first
+
+	// Sampled is set when the capture was sampled. Refusals and handshake
+	// RTTs are then left out: they match a SYN with its answer, and sampling
+	// rarely keeps both.
+	Sampled bool `json:",omitempty"`
+	// SequenceOmitted is set when retransmissions and duplicate ACKs are left
+	// out as well, because the backend flagged them from the sampled packets
+	// only. tshark flags them on the full stream, before sampling.
+	SequenceOmitted bool `json:",omitempty"`
+}
+
+// TCPHostStat holds the TCP health of one host. Retransmissions, duplicate
+// ACKs, zero windows and resets count against the host that sent them;
+// handshakes and refusals against the host that was connected to.
+type TCPHostStat struct {
+	IP                 string
+	Name               string `json:",omitempty"` // Host name learned passively, see DeviceName
+	Segments           int64
+	Retransmissions    int64
+	RetransmissionRate float64
+	DuplicateAcks      int64
+	ZeroWindows        int64
+	Resets             int64
+	Refused            int64
+	Handshakes         int64
+	AvgRTT             time.Duration
+	MaxRTT             time.Duration
+}
+
+// TCPServiceStat holds the handshakes made to one server port.
+type TCPServiceStat struct {
+	Port       int
+	Service    string
+	Handshakes int64
+	Refused    int64
+	AvgRTT     time.Duration
+	MaxRTT     time.Duration
+}
+
+// tcpTracker accumulates TCP health. It is created with the first TCP packet.
+type tcpTracker struct {
+	segments        int64
+	retransmissions int64
+	dupAcks         int64
+	zeroWindows     int64
+	resets          int64
+	refused         int64
+	handshakes      handshakeTiming
+	hosts           map[string]*tcpHostCount
+	services        map[int]*handshakeTiming
+	pending         map[flowKey]pendingSYN // SYNs waiting for a SYN-ACK
+	sampledAnalysis bool                   // Some flags were judged from sampled packets only
+}
+
+type tcpHostCount struct {
+	segments        int64
+	retransmissions int64
+	dupAcks         int64
+	zeroWindows     int64
+	resets          int64
+	handshakes      handshakeTiming
+}
+
+// handshakeTiming accumulates the outcome of the handshakes to a host or port.
+type handshakeTiming struct {
+	count   int64
+	refused int64
+	rttSum  time.Duration
+	rttMax  time.Duration
+}
+
+type pendingSYN struct {
+	client string
+	sent   time.Time
+}
+
+func newTCPTracker() *tcpTracker {
+	return &tcpTracker{
+		hosts:    make(map[string]*tcpHostCount),
+		services: make(map[int]*handshakeTiming),
+		pending:  make(map[flowKey]pendingSYN),
+	}
+}
+
+// processTCP accounts the health indicators of a TCP segment. The handshake
+// RTT is measured on packet time between the latest SYN of a connection and
+// its SYN-ACK, so it is the network round trip as seen from the capture point.
+// Must be called with s.mu held.
+func (s *TrafficStats) processTCP(pkt models.PacketData) {
+	seg := pkt.TCP
+	if seg == nil {
+		return
+	}
+	if s.tcp == nil {
+		s.tcp = newTCPTracker()
+	}
+	t := s.tcp
+
+	if seg.SampledAnalysis {
+		t.sampledAnalysis = true
+	}
+	sender := t.host(pkt.SrcIP)
+	t.segments++
+	sender.segments++
+	if seg.Retransmission {
+		t.retransmissions++
+		sender.retransmissions++
+	}
+	if seg.DuplicateAck {
+		t.dupAcks++
+		sender.dupAcks++
+	}
+	if seg.ZeroWindow {
+		t.zeroWindows++
+		sender.zeroWindows++
+	}
+	if seg.Has(models.TCPFlagRST) {
+		t.resets++
+		sender.resets++
+	}
+
+	key, ok := flowKeyOf(pkt)
+	if !ok {
+		return
+	}
+	switch {
+	case seg.Has(models.TCPFlagSYN | models.TCPFlagACK):
+		syn, ok := t.pending[key]
+		if !ok || syn.client != pkt.DstIP {
+			return
+		}
+		delete(t.pending, key)
+		if rtt := pkt.Timestamp.Sub(syn.sent); rtt >= 0 {
+			for _, h := range []*handshakeTiming{&t.handshakes, &sender.handshakes, t.service(pkt.SrcPort)} {
+				h.count++
+				h.rttSum += rtt
+				h.rttMax = max(h.rttMax, rtt)
+			}
+		}
+	case seg.Has(models.TCPFlagSYN):
+		t.addPending(key, pendingSYN{client: pkt.SrcIP, sent: pkt.Timestamp})
+	case seg.Has(models.TCPFlagRST):
+		syn, ok := t.pending[key]
+		if !ok || syn.client != pkt.DstIP {
+			return
+		}
+		delete(t.pending, key)
+		t.refused++
+		sender.handshakes.refused++
+		t.service(pkt.SrcPort).refused++
+	}
+}
+
+// host returns the counts of a host, creating them if needed.
+func (t *tcpTracker) host(ip string) *tcpHostCount {
+	h := t.hosts[ip]
+	if h == nil {
+		h = &tcpHostCount{}
+		t.hosts[ip] = h
+	}
+	return h
+}
+
+// service returns the handshakes of a server port, creating them if needed.
+func (t *tcpTracker) service(port int) *handshakeTiming {
+	h := t.services[port]
+	if h == nil {
+		h = &handshakeTiming{}
+		t.services[port] = h
+	}
+	return h
+}
+
+// addPending remembers when a SYN was sent. SYNs that went unanswered for
+// longer than synTimeout are forgotten once too many are waiting.
+func (t *tcpTracker) addPending(key flowKey, syn pendingSYN) {
+	if _, exists := t.pending[key]; !exists && len(t.pending) >= maxPendingSYN {
+		for k, p := range t.pending {
+			if syn.sent.Sub(p.sent) > synTimeout {
+				delete(t.pending, k)
+			}
+		}
+		if len(t.pending) >= maxPendingSYN {
+			return
+		}
+	}
+	t.pending[key] = syn
+}
+
+// GetTCPReport returns the TCP health, with up to limit entries per list.
+func (s *TrafficStats) GetTCPReport(limit int) TCPReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.tcp
+	if t == nil {
+		return TCPReport{}
+	}
+
+	r := TCPReport{
+		Segments:           s.scaled(t.segments),
+		Retransmissions:    s.scaled(t.retransmissions),
+		RetransmissionRate: percent(t.retransmissions, t.segments),
+		DuplicateAcks:      s.scaled(t.dupAcks),
+		ZeroWindows:        s.scaled(t.zeroWindows),
+		Resets:             s.scaled(t.resets),
+		Refused:            s.scaled(t.refused),
+		Handshakes:         s.scaled(t.handshakes.count),
+		AvgHandshakeRTT:    t.handshakes.avg(),
+	}
+
+	for ip, h := range t.hosts {
+		r.Hosts = append(r.Hosts, TCPHostStat{
+			IP:                 ip,
+			Name:               s.names.nameOf(ip),
+			Segments:           s.scaled(h.segments),
+			Retransmissions:    s.scaled(h.retransmissions),
+			RetransmissionRate: percent(h.retransmissions, h.segments),
+			DuplicateAcks:      s.scaled(h.dupAcks),
+			ZeroWindows:        s.scaled(h.zeroWindows),
+			Resets:             s.scaled(h.resets),
+			Refused:            s.scaled(h.handshakes.refused),
+			Handshakes:         s.scaled(h.handshakes.count),
+			AvgRTT:             h.handshakes.avg(),
+			MaxRTT:             h.handshakes.rttMax,
+		})
+	}
+	if s.scale > 1 {
+		r.omitPairMetrics(t.sampledAnalysis)
+	}
+
+	sort.Slice(r.Hosts, func(i, j int) bool {
+		a, b := r.Hosts[i].Problems(), r.Hosts[j].Problems()
+		if a != b {
+			return a > b
+		}
+		return r.Hosts[i].AvgRTT > r.Hosts[j].AvgRTT
+	})
+
+	for port, h := range t.services {
+		if r.Sampled {
+			break
+		}
+		r.Services = append(r.Services, TCPServiceStat{
+			Port:       port,
+			Service:    GetServiceName(port),
+			Handshakes: s.scaled(h.count),
+			Refused:    s.scaled(h.refused),
+			AvgRTT:     h.avg(),
+			MaxRTT:     h.rttMax,
+		})
+	}
+	sort.Slice(r.Services, func(i, j int) bool {
+		return r.Services[i].AvgRTT > r.Services[j].AvgRTT
+	})
+
+	r.Hosts = truncate(r.Hosts, limit)
+	r.Services = truncate(r.Services, limit)
+	return r
+}
+
+// omitPairMetrics clears the figures that cannot be estimated from a sample.
+// Retransmissions and duplicate ACKs are cleared too when sequence is set,
+// i.e. when they were flagged from the sampled packets.
+func (r *TCPReport) omitPairMetrics(sequence bool) {
+	r.Sampled, r.SequenceOmitted = true, sequence
+	r.Refused, r.Handshakes, r.AvgHandshakeRTT = 0, 0, 0
+	if sequence {
+		r.Retransmissions, r.RetransmissionRate, r.DuplicateAcks = 0, 0, 0
+	}
+	for i := range r.Hosts {
+		h := &r.Hosts[i]
+		h.Refused, h.Handshakes, h.AvgRTT, h.MaxRTT = 0, 0, 0, 0
+		if sequence {
+			h.Retransmissions, h.RetransmissionRate, h.DuplicateAcks = 0, 0, 0
+		}
+	}
+}
+
+// Problems returns the number of events pointing at a network or host problem.
+func (h TCPHostStat) Problems() int64 {
+	return h.Retransmissions + h.DuplicateAcks + h.ZeroWindows + h.Resets + h.Refused
+}
+
+// avg returns the mean RTT of the completed handshakes.
+func (h *handshakeTiming) avg() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	return h.rttSum / time.Duration(h.count)
+}
+
+// percent returns n as a percentage of total, or 0 if total is 0.
+func percent(n, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(total)
+}
@@ -0,0 +1,211 @@
+package analysis
+
+import (
+	"gonetwatch/internal/models"
+	"slices"
+	"sort"
+	"time"
+)
+
+const (
+	// maxTLSFlows bounds the number of connections whose bytes are attributed
+	// to a server name.
+	maxTLSFlows = 50000
+	// tlsFlowIdle is how long a connection may stay silent before it can be
+	// forgotten to make room for new ones.
+	tlsFlowIdle = 2 * time.Minute
+	// maxFingerprintNames is the number of example server names kept per fingerprint.
+	maxFingerprintNames = 5
+)
+
+// TLSReport summarizes the TLS connections seen so far.
+type TLSReport struct {
+	Connections  int64
+	Destinations []TLSDestinationStat // Most bytes first
+	Fingerprints []TLSFingerprintStat // Most connections first
+}
+
+// TLSDestinationStat holds the traffic of the connections to one server name.
+// Connections without SNI are listed under the server's IP address.
+type TLSDestinationStat struct {
+	ServerName  string
+	Connections int64
+	Packets     int64
+	Bytes       int64
+	ALPN        []string // Protocols negotiated by the server
+	Versions    []string // Versions negotiated by the server
+}
+
+// TLSFingerprintStat holds the connections made by one client fingerprint.
+type TLSFingerprintStat struct {
+	JA3         string
+	JA4         string `json:",omitempty"`
+	Connections int64
+	ServerNames []string // A few of the server names contacted
+}
+
+// tlsTracker attributes connections to the server name of their ClientHello.
+// It is created with the first TLS handshake.
+type tlsTracker struct {
+	connections  int64
+	flows        map[flowKey]*tlsFlow
+	destinations map[string]*tlsDestination
+	fingerprints map[string]*TLSFingerprintStat
+}
+
+type tlsFlow struct {
+	dest     *tlsDestination
+	lastSeen time.Time
+}
+
+type tlsDestination struct {
+	connections int64
+	packets     int64
+	bytes       int64
+	alpn        map[string]bool
+	versions    map[string]bool
+}
+
+func newTLSTracker() *tlsTracker {
+	return &tlsTracker{
+		flows:        make(map[flowKey]*tlsFlow),
+		destinations: make(map[string]*tlsDestination),
+		fingerprints: make(map[string]*TLSFingerprintStat),
+	}
+}
+
+// processTLS records TLS handshakes and attributes every packet of a
+// connection, the handshake included, to the server name it was opened for.
+// Must be called with s.mu held.
+func (s *TrafficStats) processTLS(pkt models.PacketData) {
+	// QUIC carries its handshake in its own packets; see processQUIC
+	if pkt.QUIC != nil {
+		return
+	}
+	if s.tls == nil {
+		if pkt.TLS == nil {
+			return
+		}
+		s.tls = newTLSTracker()
+	}
+	t := s.tls
+
+	key, ok := flowKeyOf(pkt)
+	if !ok {
+		return
+	}
+
+	if h := pkt.TLS; h != nil {
+		switch h.HandshakeType {
+		case models.TLSClientHello:
+			t.openFlow(key, pkt, h)
+		case models.TLSServerHello:
+			if flow := t.flows[key]; flow != nil {
+				if h.Version != 0 {
+					flow.dest.versions[models.TLSVersionName(h.Version)] = true
+				}
+				for _, proto := range h.ALPN {
+					flow.dest.alpn[proto] = true
+				}
+			}
+		}
+	}
+
+	flow := t.flows[key]
+	if flow == nil {
+		return
+	}
+	flow.lastSeen = pkt.Timestamp
+	flow.dest.packets++
+	flow.dest.bytes += int64(pkt.Length)
+}
+
+// openFlow starts attributing a connection to the server name of its ClientHello.
+func (t *tlsTracker) openFlow(key flowKey, pkt models.PacketData, h *models.TLSInfo) {
+	if _, exists := t.flows[key]; !exists && len(t.flows) >= maxTLSFlows {
+		for k, flow := range t.flows {
+			if pkt.Timestamp.Sub(flow.lastSeen) > tlsFlowIdle {
+				delete(t.flows, k)
+			}
+		}
+		if len(t.flows) >= maxTLSFlows {
+			return
+		}
+	}
+
+	name := h.ServerName
+	if name == "" {
+		name = pkt.DstIP
+	}
+	dest := t.destinations[name]
+	if dest == nil {
+		dest = &tlsDestination{alpn: make(map[string]bool), versions: make(map[string]bool)}
+		t.destinations[name] = dest
+	}
+	dest.connections++
+	t.connections++
+	t.flows[key] = &tlsFlow{dest: dest, lastSeen: pkt.Timestamp}
+
+	if h.JA3 != "" || h.JA4 != "" {
+		id := h.JA3 + "|" + h.JA4
+		fp := t.fingerprints[id]
+		if fp == nil {
+			fp = &TLSFingerprintStat{JA3: h.JA3, JA4: h.JA4}
+			t.fingerprints[id] = fp
+		}
+		fp.Connections++
+		if len(fp.ServerNames) < maxFingerprintNames && !slices.Contains(fp.ServerNames, name) {
+			fp.ServerNames = append(fp.ServerNames, name)
+		}
+	}
+}
+
+// GetTLSReport returns the TLS activity, with up to limit entries per list.
+func (s *TrafficStats) GetTLSReport(limit int) TLSReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.tls
+	if t == nil {
+		return TLSReport{}
+	}
+
+	r := TLSReport{Connections: s.scaled(t.connections)}
+	for name, d := range t.destinations {
+		r.Destinations = append(r.Destinations, TLSDestinationStat{
+			ServerName:  name,
+			Connections: s.scaled(d.connections),
+			Packets:     s.scaled(d.packets),
+			Bytes:       s.scaled(d.bytes),
+			ALPN:        sortedKeys(d.alpn),
+			Versions:    sortedKeys(d.versions),
+		})
+	}
+	sort.Slice(r.Destinations, func(i, j int) bool {
+		return r.Destinations[i].Bytes > r.Destinations[j].Bytes
+	})
+
+	for _, fp := range t.fingerprints {
+		stat := *fp
+		stat.Connections = s.scaled(fp.Connections)
+		stat.ServerNames = append([]string(nil), fp.ServerNames...)
+		r.Fingerprints = append(r.Fingerprints, stat)
+	}
+	sort.Slice(r.Fingerprints, func(i, j int) bool {
+		return r.Fingerprints[i].Connections > r.Fingerprints[j].Connections
+	})
+
+	r.Destinations = truncate(r.Destinations, limit)
+	r.Fingerprints = truncate(r.Fingerprints, limit)
+	return r
+}
+
+// sortedKeys returns the keys of a set in order.
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
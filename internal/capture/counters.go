@@ -0,0 +1,34 @@
+package capture
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// InterfaceCounters returns the sum of the named kernel counters of an
+// interface, e.g. "rx_packets". It relies on /sys/class/net and reports
+// false on other platforms or if a counter is missing.
+func InterfaceCounters(name string, counters ...string) (uint64, bool) {
+	var total uint64
+	for _, counter := range counters {
+		data, err := os.ReadFile(filepath.Join("/sys/class/net", name, "statistics", counter))
+		if err != nil {
+			return 0, false
+		}
+		n, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		total += n
+	}
+	return total, true
+}
+
+// InterfaceDrops returns the packets an interface dropped so far because its
+// receive buffers were full. These are the counters libpcap reports as
+// interface drops on Linux, and they can be read while any backend captures.
+func InterfaceDrops(name string) (uint64, bool) {
+	return InterfaceCounters(name, "rx_missed_errors", "rx_fifo_errors")
+}
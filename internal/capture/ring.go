@@ -0,0 +1,90 @@
+package capture
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RingBuffer configures a rotating set of pcapng files written alongside the
+// live analysis, so anything seen on the dashboard can be opened later.
+// A new file is started once the current one reaches MaxSize or has been
+// written for MaxDuration, and only the newest MaxFiles files are kept.
+type RingBuffer struct {
+	Path        string        // Base file name, e.g. /var/tmp/gonetwatch.pcapng; empty to disable
+	MaxSize     int64         // Bytes per file, 0 for no limit
+	MaxFiles    int           // Files kept, 0 to keep them all
+	MaxDuration time.Duration // Time per file, 0 for no limit
+}
+
+// ringTimeLayout is the timestamp in ring buffer file names.
+const ringTimeLayout = "20060102150405"
+
+// Enabled reports whether a ring buffer was requested.
+func (r RingBuffer) Enabled() bool {
+	return r.Path != ""
+}
+
+// FileName returns the name of the n-th file, started at t. It follows
+// dumpcap's <base>_<nnnnn>_<YYYYmmddHHMMSS><ext> scheme so the files of
+// every backend are named and ordered alike.
+func (r RingBuffer) FileName(n int, t time.Time) string {
+	ext := filepath.Ext(r.Path)
+	base := strings.TrimSuffix(r.Path, ext)
+	return fmt.Sprintf("%s_%05d_%s%s", base, n, t.Format(ringTimeLayout), ext)
+}
+
+// Files returns the ring buffer files on disk, oldest first.
+// Files are ordered by their start time, then by their number.
+func (r RingBuffer) Files() ([]string, error) {
+	ext := filepath.Ext(r.Path)
+	base := strings.TrimSuffix(r.Path, ext)
+	matches, err := filepath.Glob(base + "_*_*" + ext)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	keys := make(map[string]string)
+	for _, m := range matches {
+		// <nnnnn>_<YYYYmmddHHMMSS>
+		n, ts, ok := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(m, base+"_"), ext), "_")
+		if !ok || len(ts) != len(ringTimeLayout) {
+			continue
+		}
+		keys[m] = ts + strings.Repeat("0", max(0, 10-len(n))) + n
+		files = append(files, m)
+	}
+	sort.Slice(files, func(i, j int) bool { return keys[files[i]] < keys[files[j]] })
+	return files, nil
+}
+
+// Current returns the file being written, or "" if there is none yet.
+func (r RingBuffer) Current() string {
+	files, err := r.Files()
+	if err != nil || len(files) == 0 {
+		return ""
+	}
+	return files[len(files)-1]
+}
+
+// Prune deletes the oldest files beyond MaxFiles.
+func (r RingBuffer) Prune() error {
+	if r.MaxFiles <= 0 {
+		return nil
+	}
+	files, err := r.Files()
+	if err != nil {
+		return err
+	}
+	for len(files) > r.MaxFiles {
+		if err := os.Remove(files[0]); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		files = files[1:]
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package capture
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRingBufferFileName(t *testing.T) {
+	start := time.Date(2024, 3, 5, 14, 7, 9, 0, time.UTC)
+	tests := []struct {
+		path string
+		n    int
+		want string
+	}{
+		{"/tmp/cap.pcapng", 1, "/tmp/cap_00001_20240305140709.pcapng"},
+		{"/tmp/cap", 42, "/tmp/cap_00042_20240305140709"},
+		{"/tmp/cap.pcapng", 123456, "/tmp/cap_123456_20240305140709.pcapng"},
+	}
+	for _, tt := range tests {
+		if got := (RingBuffer{Path: tt.path}).FileName(tt.n, start); got != tt.want {
+			t.Errorf("FileName(%d) for %s = %s, want %s", tt.n, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRingBufferFilesAndPrune(t *testing.T) {
+	start := time.Date(2024, 3, 5, 14, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		files    []int // File numbers, each started a minute after the previous one
+		maxFiles int
+		wantKept []int
+	}{
+		{"under the limit", []int{1, 2}, 3, []int{1, 2}},
+		{"oldest removed", []int{1, 2, 3, 4}, 2, []int{3, 4}},
+		{"no limit", []int{1, 2, 3}, 0, []int{1, 2, 3}},
+		// A restarted capture numbers its files afresh, so time orders them
+		{"restart renumbers", []int{7, 8, 1, 2}, 3, []int{8, 1, 2}},
+		{"more than five digits", []int{99999, 100000}, 1, []int{100000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			r := RingBuffer{Path: filepath.Join(dir, "cap.pcapng"), MaxFiles: tt.maxFiles}
+			names := make(map[string]int)
+			for i, n := range tt.files {
+				name := r.FileName(n, start.Add(time.Duration(i)*time.Minute))
+				if err := os.WriteFile(name, nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				names[name] = n
+			}
+			// Files of other captures in the same directory are left alone
+			if err := os.WriteFile(filepath.Join(dir, "other_00001_20240305140000.pcapng"), nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "cap_notes.pcapng"), nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			files, err := r.Files()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := numbers(files, names); !slices.Equal(got, tt.files) {
+				t.Errorf("Files() = %v, want %v", got, tt.files)
+			}
+			if err := r.Prune(); err != nil {
+				t.Fatal(err)
+			}
+			files, err = r.Files()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := numbers(files, names); !slices.Equal(got, tt.wantKept) {
+				t.Errorf("after Prune() files = %v, want %v", got, tt.wantKept)
+			}
+			if got := names[r.Current()]; got != tt.wantKept[len(tt.wantKept)-1] {
+				t.Errorf("Current() = file %d, want %d", got, tt.wantKept[len(tt.wantKept)-1])
+			}
+		})
+	}
+}
+
+// numbers maps file names back to the file numbers they were created with.
+func numbers(files []string, names map[string]int) []int {
+	var ns []int
+	for _, f := range files {
+		ns = append(ns, names[f])
+	}
+	return ns
+}
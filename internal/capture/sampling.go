@@ -0,0 +1,83 @@
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// Sampling configures packet sampling at the capture stage. Under heavy load
+// only a share of the packets is decoded and analyzed, and the analysis
+// scales its counters back up by Scale.
+type Sampling struct {
+	Every       int     // Keep one packet in Every, counting deterministically; 0 disables
+	Probability float64 // Keep each packet with this probability; 0 disables
+}
+
+// Enabled reports whether packets are sampled.
+func (s Sampling) Enabled() bool {
+	return s.Every > 1 || (s.Probability > 0 && s.Probability < 1)
+}
+
+// Validate checks that at most one sampling method is set, with a usable value.
+func (s Sampling) Validate() error {
+	if s.Every < 0 {
+		return fmt.Errorf("invalid sampling rate 1 in %d", s.Every)
+	}
+	if s.Probability < 0 || s.Probability > 1 {
+		return fmt.Errorf("invalid sampling probability %g, must be between 0 and 1", s.Probability)
+	}
+	if s.Every > 1 && s.Probability > 0 {
+		return errors.New("choose either 1-in-N or probabilistic sampling, not both")
+	}
+	return nil
+}
+
+// Scale is the number of packets each sampled packet stands for.
+func (s Sampling) Scale() float64 {
+	switch {
+	case s.Every > 1:
+		return float64(s.Every)
+	case s.Probability > 0 && s.Probability < 1:
+		return 1 / s.Probability
+	}
+	return 1
+}
+
+// String describes the sampling, e.g. "1 in 100" or "random 1%".
+func (s Sampling) String() string {
+	switch {
+	case s.Every > 1:
+		return fmt.Sprintf("1 in %d", s.Every)
+	case s.Probability > 0 && s.Probability < 1:
+		return fmt.Sprintf("random %g%%", s.Probability*100)
+	}
+	return "none"
+}
+
+// Sampler decides which packets are kept. It is safe for concurrent use.
+type Sampler struct {
+	cfg  Sampling
+	seen atomic.Uint64
+}
+
+// NewSampler returns a sampler for cfg, or nil if sampling is disabled.
+// A nil Sampler keeps every packet.
+func NewSampler(cfg Sampling) *Sampler {
+	if !cfg.Enabled() {
+		return nil
+	}
+	return &Sampler{cfg: cfg}
+}
+
+// Keep reports whether the next packet should be analyzed.
+func (s *Sampler) Keep() bool {
+	if s == nil {
+		return true
+	}
+	if s.cfg.Every > 1 {
+		return s.seen.Add(1)%uint64(s.cfg.Every) == 1
+	}
+	return rand.Float64() < s.cfg.Probability
+}
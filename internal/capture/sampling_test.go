@@ -0,0 +1,50 @@
+package capture
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSamplerKeep(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Sampling
+		n    int
+		want []int // Indexes of the kept packets, counting from 0
+	}{
+		{"disabled", Sampling{}, 3, []int{0, 1, 2}},
+		{"every packet", Sampling{Every: 1}, 3, []int{0, 1, 2}},
+		{"1 in 2", Sampling{Every: 2}, 6, []int{0, 2, 4}},
+		{"1 in 3", Sampling{Every: 3}, 7, []int{0, 3, 6}},
+		{"certain", Sampling{Probability: 1}, 3, []int{0, 1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSampler(tt.cfg)
+			var kept []int
+			for i := range tt.n {
+				if s.Keep() {
+					kept = append(kept, i)
+				}
+			}
+			if !slices.Equal(kept, tt.want) {
+				t.Errorf("kept packets %v, want %v", kept, tt.want)
+			}
+		})
+	}
+}
+
+func TestSamplerKeepProbability(t *testing.T) {
+	const n = 10000
+	s := NewSampler(Sampling{Probability: 0.1})
+	kept := 0
+	for range n {
+		if s.Keep() {
+			kept++
+		}
+	}
+	// Far outside any plausible spread around the expected 1000
+	if kept < 700 || kept > 1300 {
+		t.Errorf("kept %d of %d packets at probability 0.1", kept, n)
+	}
+}
@@ -0,0 +1,141 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"gonetwatch/internal/models"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Source produces decoded packets from some capture backend.
+type Source interface {
+	// Start begins capturing in the background. The capture stops when ctx
+	// is cancelled or Stop is called.
+	Start(ctx context.Context) error
+	// Stop halts the capture and releases its resources.
+	Stop() error
+	// Packets delivers decoded packets. It is closed once the source is
+	// exhausted (e.g. end of file) or stopped.
+	Packets() <-chan models.PacketData
+	// Errors reports problems encountered while capturing.
+	Errors() <-chan error
+	// Stats returns counters describing the capture so far.
+	Stats() Stats
+}
+
+// Validator is implemented by sources that can check their configuration,
+// e.g. filter syntax, before the capture starts.
+type Validator interface {
+	Validate() error
+}
+
+// Refilterer is implemented by sources whose filters can be changed while capturing.
+type Refilterer interface {
+	SetFilters(captureFilter, displayFilter string) error
+}
+
+// CombineFilters joins BPF capture filters so that a packet must match all of them.
+// Empty filters are ignored.
+func CombineFilters(filters ...string) string {
+	var parts []string
+	for _, f := range filters {
+		if f = strings.TrimSpace(f); f != "" {
+			parts = append(parts, f)
+		}
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	for i := range parts {
+		parts[i] = "(" + parts[i] + ")"
+	}
+	return strings.Join(parts, " and ")
+}
+
+// Stats holds counters reported by a Source.
+type Stats struct {
+	Packets       int64 // Packets delivered on the Packets channel
+	Restarts      int64 // Times the backend had to be restarted after a failure
+	KernelDrops   int64 // Packets dropped by the kernel or interface before the backend saw them
+	PipelineDrops int64 // Packets dropped because the consumer could not keep up
+	Malformed     int64 // Backend output that could not be decoded
+	Skipped       int64 // Frames or output lines ignored without being delivered
+	SampledOut    int64 // Packets left out by sampling
+
+	// KernelDropsPending is set while part of the kernel drops of the running
+	// capture is still unknown: tshark only reports the drops of its capture
+	// buffer when it exits. KernelDrops then covers the interface drops read
+	// so far and the buffer drops of earlier runs.
+	KernelDropsPending bool `json:",omitempty"`
+
+	RingFile string `json:",omitempty"` // Ring buffer file currently being written
+}
+
+// Lost returns the number of packets known to be missing from the analysis.
+// When it is non-zero the statistics undercount the real traffic.
+func (s Stats) Lost() int64 {
+	return s.KernelDrops + s.PipelineDrops + s.Malformed
+}
+
+// Config describes what a backend should capture.
+type Config struct {
+	Interfaces    []string // Live interface names, empty when reading a file
+	File          string   // Capture file path, empty when capturing live
+	CaptureFilter string   // BPF capture filter
+	DisplayFilter string   // Wireshark display filter
+
+	ExtraFields  []models.FieldSpec // Additional fields to extract onto PacketData.Extra
+	OutputFormat string             // Backend output format, e.g. tshark's "ek" or "fields"; empty for the default
+	Ring         RingBuffer         // Packets to also write to disk, live captures only
+	Sampling     Sampling           // Share of the packets to decode and deliver
+}
+
+// Factory creates a Source for the given configuration.
+type Factory func(cfg Config) (Source, error)
+
+var (
+	backendsMu sync.Mutex
+	backends   = make(map[string]Factory)
+)
+
+// Register makes a backend available under the given name.
+// It is meant to be called from the init function of the backend package.
+func Register(name string, factory Factory) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	if _, dup := backends[name]; dup {
+		panic("capture: Register called twice for backend " + name)
+	}
+	backends[name] = factory
+}
+
+// New creates a Source using the named backend.
+func New(name string, cfg Config) (Source, error) {
+	backendsMu.Lock()
+	factory, ok := backends[name]
+	backendsMu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown capture backend %q (available: %v)", name, Backends())
+	}
+	if err := cfg.Sampling.Validate(); err != nil {
+		return nil, err
+	}
+	return factory(cfg)
+}
+
+// Backends returns the names of all registered backends.
+func Backends() []string {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
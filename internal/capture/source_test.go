@@ -0,0 +1,25 @@
+package capture
+
+import "testing"
+
+func TestCombineFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters []string
+		want    string
+	}{
+		{"none", nil, ""},
+		{"only empty", []string{"", "  "}, ""},
+		{"single", []string{"tcp port 80"}, "tcp port 80"},
+		{"single among empty", []string{"", " udp ", ""}, "udp"},
+		{"two", []string{"tcp", "not host 10.0.0.1"}, "(tcp) and (not host 10.0.0.1)"},
+		{"or kept together", []string{"port 53 or port 5353", "udp"}, "(port 53 or port 5353) and (udp)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CombineFilters(tt.filters...); got != tt.want {
+				t.Errorf("CombineFilters(%q) = %q, want %q", tt.filters, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"gonetwatch/internal/analysis"
+	"gonetwatch/internal/capture"
+	"os"
+	"time"
+)
+
+const (
+	topTalkersLimit  = 50
+	fieldValuesLimit = 20
+	devicesLimit     = 1000
+)
+
+// Report is a snapshot of the analysis results, written out when GoNetWatch exits.
+type Report struct {
+	GeneratedAt     time.Time
+	Source          string // Interface name or capture file
+	Capture         capture.Stats
+	Incomplete      bool    // Packets were lost, so the figures undercount the traffic
+	Estimated       bool    // The capture was sampled and the figures are scaled up
+	SampleScale     float64 `json:",omitempty"` // Packets each analyzed packet stands for
+	FirstSeen       time.Time
+	LastSeen        time.Time
+	Packets         int64
+	Bytes           int64
+	TopTalkers      []analysis.IPStat
+	InnerTopTalkers []analysis.IPStat `json:",omitempty"` // Tunneled traffic attributed to its inner source addresses
+	Protocols       []analysis.ProtocolStat
+	Families        []analysis.FamilyStat
+	Links           []analysis.LinkStat
+	Fields          map[string][]analysis.FieldValueStat `json:",omitempty"`
+	Interfaces      []InterfaceReport                    `json:",omitempty"`
+	Segments        []InterfaceReport                    `json:",omitempty"` // Per VLAN and tunnel network
+	DNS             *analysis.DNSReport                  `json:",omitempty"`
+	TLS             *analysis.TLSReport                  `json:",omitempty"`
+	HTTP            *analysis.HTTPReport                 `json:",omitempty"`
+	TCP             *analysis.TCPReport                  `json:",omitempty"`
+	ICMP            *analysis.ICMPReport                 `json:",omitempty"`
+	QUIC            *analysis.QUICReport                 `json:",omitempty"`
+	Devices         []analysis.DeviceName                `json:",omitempty"` // Host names and MACs learned passively, by IP
+}
+
+// InterfaceReport is the breakdown of a capture for one interface, or for one
+// VLAN/tunnel segment (see analysis.SegmentName).
+type InterfaceReport struct {
+	Name       string
+	FirstSeen  time.Time
+	LastSeen   time.Time
+	Packets    int64
+	Bytes      int64
+	TopTalkers []analysis.IPStat
+	Protocols  []analysis.ProtocolStat
+}
+
+// Build collects a report from the current statistics and the capture counters.
+func Build(stats *analysis.TrafficStats, source string, capStats capture.Stats) Report {
+	r := Report{
+		GeneratedAt: time.Now(),
+		Source:      source,
+		Capture:     capStats,
+		Incomplete:  capStats.Lost() > 0,
+		TopTalkers:  stats.GetTopTalkers(topTalkersLimit),
+		Protocols:   stats.GetProtocolStats(),
+		Families:    stats.GetFamilyStats(),
+		Links:       stats.GetLinkStats(),
+	}
+	r.Packets, r.Bytes = stats.GetTotals()
+	r.FirstSeen, r.LastSeen = stats.GetTimeSpan()
+	if scale := stats.SampleScale(); scale > 1 {
+		r.Estimated, r.SampleScale = true, scale
+	}
+
+	if names := stats.GetFieldNames(); len(names) > 0 {
+		r.Fields = make(map[string][]analysis.FieldValueStat, len(names))
+		for _, name := range names {
+			r.Fields[name] = stats.GetTopFieldValues(name, fieldValuesLimit)
+		}
+	}
+
+	if dns := stats.GetDNSReport(topTalkersLimit); dns.Queries > 0 || dns.Responses > 0 {
+		r.DNS = &dns
+	}
+	if tls := stats.GetTLSReport(topTalkersLimit); tls.Connections > 0 {
+		r.TLS = &tls
+	}
+	if http := stats.GetHTTPReport(topTalkersLimit); http.Requests > 0 || http.Responses > 0 {
+		r.HTTP = &http
+	}
+	if tcp := stats.GetTCPReport(topTalkersLimit); tcp.Segments > 0 {
+		r.TCP = &tcp
+	}
+	if icmp := stats.GetICMPReport(topTalkersLimit); icmp.Messages > 0 {
+		r.ICMP = &icmp
+	}
+	if quic := stats.GetQUICReport(topTalkersLimit); quic.Connections > 0 {
+		r.QUIC = &quic
+	}
+	r.Devices = stats.GetDeviceNames(devicesLimit)
+
+	if stats.HasTunnels() {
+		r.InnerTopTalkers = stats.GetTopTalkersBy(analysis.AddressInner, topTalkersLimit)
+	}
+
+	for _, name := range stats.GetInterfaces() {
+		r.Interfaces = append(r.Interfaces, breakdown(name, stats.ForInterface(name)))
+	}
+	for _, name := range stats.GetSegments() {
+		r.Segments = append(r.Segments, breakdown(name, stats.ForSegment(name)))
+	}
+	return r
+}
+
+// breakdown summarizes the stats of one interface or segment.
+func breakdown(name string, child *analysis.TrafficStats) InterfaceReport {
+	ir := InterfaceReport{
+		Name:       name,
+		TopTalkers: child.GetTopTalkers(topTalkersLimit),
+		Protocols:  child.GetProtocolStats(),
+	}
+	ir.Packets, ir.Bytes = child.GetTotals()
+	ir.FirstSeen, ir.LastSeen = child.GetTimeSpan()
+	return ir
+}
+
+// WriteJSON writes the report as indented JSON to path.
+func WriteJSON(path string, r Report) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %v", err)
+	}
+	return nil
+}
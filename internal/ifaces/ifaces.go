@@ -0,0 +1,123 @@
+// Package ifaces discovers the network interfaces packets can be captured
+// from, so an operator can pick one without knowing the host.
+package ifaces
+
+import (
+	"gonetwatch/internal/capture"
+	"gonetwatch/internal/tshark"
+	"net"
+	"sort"
+	"time"
+)
+
+// Link states reported by List.
+const (
+	StateUp        = "up"
+	StateNoCarrier = "no carrier" // Enabled, but the link is down
+	StateDown      = "down"
+	StateUnknown   = "-" // Only known to tshark, e.g. "any" or USB monitors
+)
+
+// Interface describes a capture interface.
+type Interface struct {
+	Name        string
+	Description string   // From tshark, may be empty
+	MAC         string   // Empty for interfaces without a hardware address
+	Addresses   []string // IP addresses in CIDR notation
+	State       string
+	Loopback    bool
+}
+
+// List returns the capture interfaces of this host. Interfaces known to the
+// OS come first with their addresses and link state, sorted by name; those
+// only tshark can capture from (e.g. "any") follow. tshark is optional.
+func List() ([]Interface, error) {
+	osIfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	descriptions := make(map[string]string)
+	tsIfaces, _ := tshark.ListInterfaces()
+	for _, ti := range tsIfaces {
+		descriptions[ti.Name] = ti.Description
+	}
+
+	var list []Interface
+	known := make(map[string]bool)
+	for _, oi := range osIfaces {
+		iface := Interface{
+			Name:        oi.Name,
+			Description: descriptions[oi.Name],
+			MAC:         oi.HardwareAddr.String(),
+			State:       linkState(oi.Flags),
+			Loopback:    oi.Flags&net.FlagLoopback != 0,
+		}
+		if addrs, err := oi.Addrs(); err == nil {
+			for _, a := range addrs {
+				iface.Addresses = append(iface.Addresses, a.String())
+			}
+		}
+		list = append(list, iface)
+		known[oi.Name] = true
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	for _, ti := range tsIfaces {
+		if !known[ti.Name] {
+			list = append(list, Interface{Name: ti.Name, Description: ti.Description, State: StateUnknown})
+		}
+	}
+	return list, nil
+}
+
+func linkState(flags net.Flags) string {
+	switch {
+	case flags&net.FlagUp == 0:
+		return StateDown
+	case flags&net.FlagRunning == 0:
+		return StateNoCarrier
+	default:
+		return StateUp
+	}
+}
+
+// RateSampler measures the packet rate of interfaces from the kernel's
+// counters, without capturing. It relies on /sys/class/net and reports
+// no rate on other platforms.
+type RateSampler struct {
+	last map[string]uint64
+	at   time.Time
+}
+
+// NewRateSampler creates a sampler. The first Sample only sets the baseline.
+func NewRateSampler() *RateSampler {
+	return &RateSampler{last: make(map[string]uint64)}
+}
+
+// Sample returns the packets per second (received and sent) of each named
+// interface since the previous call. Interfaces without counters, and every
+// interface on the first call, are missing from the result.
+func (r *RateSampler) Sample(names []string) map[string]float64 {
+	now := time.Now()
+	elapsed := now.Sub(r.at).Seconds()
+	r.at = now
+
+	rates := make(map[string]float64)
+	for _, name := range names {
+		count, ok := packetCount(name)
+		if !ok {
+			continue
+		}
+		if prev, seen := r.last[name]; seen && elapsed > 0 && count >= prev {
+			rates[name] = float64(count-prev) / elapsed
+		}
+		r.last[name] = count
+	}
+	return rates
+}
+
+// packetCount returns the packets received and sent by an interface so far.
+func packetCount(name string) (uint64, bool) {
+	return capture.InterfaceCounters(name, "rx_packets", "tx_packets")
+}
@@ -0,0 +1,48 @@
+package models
+
+import "strconv"
+
+// DNSInfo is the DNS message carried by a packet.
+type DNSInfo struct {
+	ID        uint16
+	Response  bool
+	QueryName string   // Name of the first question
+	QueryType uint16   // Type of the first question (1 = A, 28 = AAAA...)
+	RCode     int      // Response code, 0 for queries
+	Answers   []string // Answer data: addresses, CNAME targets...
+}
+
+// DNS response codes of interest.
+const (
+	DNSRCodeNoError  = 0
+	DNSRCodeServFail = 2
+	DNSRCodeNXDomain = 3
+	DNSRCodeRefused  = 5
+)
+
+var dnsTypeNames = map[uint16]string{
+	1: "A", 2: "NS", 5: "CNAME", 6: "SOA", 12: "PTR", 15: "MX", 16: "TXT",
+	28: "AAAA", 33: "SRV", 35: "NAPTR", 43: "DS", 46: "RRSIG", 48: "DNSKEY",
+	64: "SVCB", 65: "HTTPS", 255: "ANY",
+}
+
+// DNSTypeName returns the mnemonic of a DNS record type, e.g. "AAAA",
+// or "TYPEn" for types without one.
+func DNSTypeName(t uint16) string {
+	if name, ok := dnsTypeNames[t]; ok {
+		return name
+	}
+	return "TYPE" + strconv.Itoa(int(t))
+}
+
+var dnsRCodeNames = map[int]string{
+	0: "NOERROR", 1: "FORMERR", 2: "SERVFAIL", 3: "NXDOMAIN", 4: "NOTIMP", 5: "REFUSED",
+}
+
+// DNSRCodeName returns the name of a DNS response code, e.g. "NXDOMAIN".
+func DNSRCodeName(rcode int) string {
+	if name, ok := dnsRCodeNames[rcode]; ok {
+		return name
+	}
+	return "RCODE" + strconv.Itoa(rcode)
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FieldKind is the type of a user-requested extra field.
+type FieldKind int
+
+const (
+	FieldAuto FieldKind = iota // Let the backend pick the type
+	FieldString
+	FieldInt
+	FieldFloat
+	FieldBool
+)
+
+var fieldKindNames = map[FieldKind]string{
+	FieldAuto:   "auto",
+	FieldString: "string",
+	FieldInt:    "int",
+	FieldFloat:  "float",
+	FieldBool:   "bool",
+}
+
+// String returns the name used for the kind in field specs.
+func (k FieldKind) String() string {
+	return fieldKindNames[k]
+}
+
+// FieldSpec names an extra field to extract, e.g. "dns.qry.name" or "vlan.id:int".
+type FieldSpec struct {
+	Name string
+	Kind FieldKind
+}
+
+// ParseFieldSpec parses "name" or "name:kind".
+func ParseFieldSpec(s string) (FieldSpec, error) {
+	name, kindStr, hasKind := strings.Cut(strings.TrimSpace(s), ":")
+	if name == "" {
+		return FieldSpec{}, fmt.Errorf("empty field name in %q", s)
+	}
+
+	spec := FieldSpec{Name: name}
+	if !hasKind {
+		return spec, nil
+	}
+	for kind, kindName := range fieldKindNames {
+		if kindName == kindStr {
+			spec.Kind = kind
+			return spec, nil
+		}
+	}
+	return FieldSpec{}, fmt.Errorf("unknown type %q for field %s (want string, int, float or bool)", kindStr, name)
+}
+
+// FieldValue is one typed occurrence of an extra field.
+type FieldValue struct {
+	Kind  FieldKind
+	Str   string
+	Int   int64
+	Float float64
+	Bool  bool
+}
+
+// ParseFieldValue converts a raw value into the given kind.
+// Integers may be written in decimal or with a 0x prefix, as tshark does for hex fields.
+func ParseFieldValue(kind FieldKind, raw string) (FieldValue, error) {
+	v := FieldValue{Kind: kind}
+	var err error
+
+	switch kind {
+	case FieldInt:
+		v.Int, err = strconv.ParseInt(raw, 0, 64)
+	case FieldFloat:
+		v.Float, err = strconv.ParseFloat(raw, 64)
+	case FieldBool:
+		switch strings.ToLower(raw) {
+		case "1", "true", "yes":
+			v.Bool = true
+		case "0", "false", "no":
+		default:
+			err = fmt.Errorf("invalid boolean %q", raw)
+		}
+	default:
+		v.Kind = FieldString
+		v.Str = raw
+	}
+	return v, err
+}
+
+// String formats the value for display and aggregation.
+func (v FieldValue) String() string {
+	switch v.Kind {
+	case FieldInt:
+		return strconv.FormatInt(v.Int, 10)
+	case FieldFloat:
+		return strconv.FormatFloat(v.Float, 'f', -1, 64)
+	case FieldBool:
+		return strconv.FormatBool(v.Bool)
+	default:
+		return v.Str
+	}
+}
@@ -0,0 +1,11 @@
+package models
+
+// HTTPInfo is the start of an HTTP/1.x request or response carried by a packet.
+type HTTPInfo struct {
+	Request    bool
+	Method     string // Requests only
+	Host       string // Requests only, from the Host header
+	Path       string // Requests only, URI without the query string
+	UserAgent  string // Requests only
+	StatusCode int    // Responses only
+}
@@ -0,0 +1,129 @@
+package models
+
+import "fmt"
+
+// ICMPKind groups ICMP and ICMPv6 message types by meaning, so both
+// versions can be counted together.
+type ICMPKind int
+
+const (
+	ICMPOther ICMPKind = iota
+	ICMPEchoRequest
+	ICMPEchoReply
+	ICMPUnreachable
+	ICMPTimeExceeded
+	ICMPRedirect
+	ICMPPacketTooBig // ICMPv6 Packet Too Big, or ICMP Fragmentation Needed
+)
+
+// ICMPInfo is the ICMP or ICMPv6 header of a packet.
+type ICMPInfo struct {
+	V6   bool // ICMPv6
+	Type uint8
+	Code uint8
+	ID   uint16 // Echo identifier
+	Seq  uint16 // Echo sequence number
+	MTU  int    // Next-hop MTU of a Packet Too Big / Fragmentation Needed message
+}
+
+// QuotesPacket reports whether the message is an error or redirect, which
+// carries the start of the packet that caused it.
+func (i *ICMPInfo) QuotesPacket() bool {
+	if i.V6 {
+		return i.Type < 128 || i.Type == 137
+	}
+	switch i.Type {
+	case 3, 4, 5, 11, 12:
+		return true
+	}
+	return false
+}
+
+// Kind classifies the message.
+func (i *ICMPInfo) Kind() ICMPKind {
+	if i.V6 {
+		switch i.Type {
+		case 128:
+			return ICMPEchoRequest
+		case 129:
+			return ICMPEchoReply
+		case 1:
+			return ICMPUnreachable
+		case 2:
+			return ICMPPacketTooBig
+		case 3:
+			return ICMPTimeExceeded
+		case 137:
+			return ICMPRedirect
+		}
+		return ICMPOther
+	}
+
+	switch i.Type {
+	case 8:
+		return ICMPEchoRequest
+	case 0:
+		return ICMPEchoReply
+	case 3:
+		if i.Code == 4 {
+			return ICMPPacketTooBig
+		}
+		return ICMPUnreachable
+	case 11:
+		return ICMPTimeExceeded
+	case 5:
+		return ICMPRedirect
+	}
+	return ICMPOther
+}
+
+var icmpNames = map[[2]uint8]string{
+	{0, 0}:  "Echo reply",
+	{8, 0}:  "Echo request",
+	{3, 0}:  "Net unreachable",
+	{3, 1}:  "Host unreachable",
+	{3, 2}:  "Protocol unreachable",
+	{3, 3}:  "Port unreachable",
+	{3, 4}:  "Fragmentation needed",
+	{3, 5}:  "Source route failed",
+	{3, 6}:  "Destination network unknown",
+	{3, 7}:  "Destination host unknown",
+	{3, 9}:  "Network administratively prohibited",
+	{3, 10}: "Host administratively prohibited",
+	{3, 13}: "Communication administratively prohibited",
+	{5, 0}:  "Redirect for network",
+	{5, 1}:  "Redirect for host",
+	{11, 0}: "TTL exceeded in transit",
+	{11, 1}: "Fragment reassembly time exceeded",
+}
+
+var icmpv6Names = map[[2]uint8]string{
+	{128, 0}: "Echo request",
+	{129, 0}: "Echo reply",
+	{1, 0}:   "No route to destination",
+	{1, 1}:   "Administratively prohibited",
+	{1, 3}:   "Address unreachable",
+	{1, 4}:   "Port unreachable",
+	{1, 5}:   "Source address failed policy",
+	{1, 6}:   "Reject route to destination",
+	{2, 0}:   "Packet too big",
+	{3, 0}:   "Hop limit exceeded in transit",
+	{3, 1}:   "Fragment reassembly time exceeded",
+	{133, 0}: "Router solicitation",
+	{134, 0}: "Router advertisement",
+	{135, 0}: "Neighbor solicitation",
+	{136, 0}: "Neighbor advertisement",
+	{137, 0}: "Redirect",
+}
+
+// Name returns the name of the message type and code, e.g. "Port unreachable".
+func (i *ICMPInfo) Name() string {
+	names, prefix := icmpNames, "ICMP"
+	if i.V6 {
+		names, prefix = icmpv6Names, "ICMPv6"
+	}
+	if name, ok := names[[2]uint8{i.Type, i.Code}]; ok {
+		return name
+	}
+	return fmt.Sprintf("%s type %d code %d", prefix, i.Type, i.Code)
+}
@@ -0,0 +1,35 @@
+package models
+
+import "testing"
+
+func TestICMPInfoQuotesPacket(t *testing.T) {
+	tests := []struct {
+		name string
+		info ICMPInfo
+		want bool
+	}{
+		{"echo reply", ICMPInfo{Type: 0}, false},
+		{"unreachable", ICMPInfo{Type: 3, Code: 4}, true},
+		{"source quench", ICMPInfo{Type: 4}, true},
+		{"redirect", ICMPInfo{Type: 5}, true},
+		{"echo request", ICMPInfo{Type: 8}, false},
+		{"time exceeded", ICMPInfo{Type: 11}, true},
+		{"parameter problem", ICMPInfo{Type: 12}, true},
+		{"timestamp", ICMPInfo{Type: 13}, false},
+		{"v6 unreachable", ICMPInfo{V6: true, Type: 1}, true},
+		{"v6 packet too big", ICMPInfo{V6: true, Type: 2, MTU: 1280}, true},
+		{"v6 time exceeded", ICMPInfo{V6: true, Type: 3}, true},
+		{"v6 parameter problem", ICMPInfo{V6: true, Type: 4}, true},
+		{"v6 echo request", ICMPInfo{V6: true, Type: 128}, false},
+		{"v6 echo reply", ICMPInfo{V6: true, Type: 129}, false},
+		{"v6 neighbor solicitation", ICMPInfo{V6: true, Type: 135}, false},
+		{"v6 redirect", ICMPInfo{V6: true, Type: 137}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.info.QuotesPacket(); got != tt.want {
+				t.Errorf("QuotesPacket() for type %d = %v, want %v", tt.info.Type, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package models
+
+// NameSource identifies the protocol a host name was learned from.
+type NameSource string
+
+const (
+	NameDHCP  NameSource = "DHCP"
+	NameMDNS  NameSource = "mDNS"
+	NameNBNS  NameSource = "NBNS"
+	NameLLMNR NameSource = "LLMNR"
+)
+
+// HostName is a name a packet announces for a host: a DHCP client's host name,
+// an mDNS, LLMNR or NetBIOS answer or registration. A DHCP request may carry
+// no address yet; the ACK that follows binds one to the client's MAC.
+type HostName struct {
+	Source   NameSource
+	Name     string // Empty for a DHCP ACK that only binds an address
+	IP       string // Empty unless the packet says which address the name belongs to
+	MAC      string // Client hardware address, only carried by DHCP
+	ClientID string // DHCP client identifier in hex, e.g. "01aabbccddeeff"
+}
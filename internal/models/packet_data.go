@@ -1,14 +1,74 @@
 package models
 
-import "time"
+import (
+	"net/netip"
+	"time"
+)
+
+// AddressFamily identifies the network-layer address family of a packet.
+type AddressFamily int
+
+const (
+	FamilyNone AddressFamily = iota // No IP layer
+	FamilyIPv4
+	FamilyIPv6
+)
+
+// String returns the conventional name of the address family.
+func (f AddressFamily) String() string {
+	switch f {
+	case FamilyIPv4:
+		return "IPv4"
+	case FamilyIPv6:
+		return "IPv6"
+	default:
+		return "Non-IP"
+	}
+}
+
+// MarshalText encodes the family by name in exports.
+func (f AddressFamily) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
 
 // PacketData holds the extracted information from a network packet.
 type PacketData struct {
 	Timestamp time.Time
+	Interface string // Capture interface the packet was seen on
+	SrcMAC    string
+	DstMAC    string
+	EtherType uint16   // Innermost EtherType, 0 for 802.3/LLC frames
+	VLANs     []uint16 // 802.1Q/802.1ad VLAN IDs, outermost first
+	Family    AddressFamily
 	SrcIP     string
 	DstIP     string
 	SrcPort   int
 	DstPort   int
-	Protocol  string
+	Protocol  string   // Transport-level label: TCP, UDP, ICMP...
+	Protocols []string // Dissection chain, outermost first (e.g. eth, ethertype, ip, tcp, tls)
 	Length    int
+
+	Tunnel *TunnelInfo // nil unless the packet is GRE, VXLAN or GENEVE encapsulated
+
+	TCP  *TCPInfo  // nil unless the packet has a TCP header
+	ICMP *ICMPInfo // nil unless the packet is an ICMP or ICMPv6 message
+	DNS  *DNSInfo  // nil unless the packet carries a DNS message
+	TLS  *TLSInfo  // nil unless the packet carries a TLS ClientHello or ServerHello
+	QUIC *QUICInfo // nil unless the packet is QUIC
+	HTTP *HTTPInfo // nil unless the packet starts an HTTP request or response
+
+	Names []HostName // Host names announced by DHCP, mDNS, NBNS or LLMNR
+
+	// Extra holds user-requested fields by name, one value per occurrence.
+	Extra map[string][]FieldValue
+}
+
+// CanonicalIP returns the canonical text form of an IP address, i.e. the
+// RFC 5952 compressed form for IPv6. Unparseable input is returned unchanged.
+func CanonicalIP(s string) string {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return s
+	}
+	return addr.String()
 }
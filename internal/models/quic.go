@@ -0,0 +1,46 @@
+package models
+
+import "fmt"
+
+// QUIC long header packet types, as numbered by QUIC version 1.
+const (
+	QUICInitial   = 0
+	QUICZeroRTT   = 1
+	QUICHandshake = 2
+	QUICRetry     = 3
+)
+
+// QUIC versions with a name.
+const (
+	QUICVersionNegotiation = 0x00000000
+	QUICVersion1           = 0x00000001
+	QUICVersion2           = 0x6b3343cf
+)
+
+// QUICInfo is the header of the first QUIC packet in a UDP datagram.
+// Connection IDs are lowercase hex; short headers carry no source CID and
+// their destination CID is only known once the connection has been seen.
+type QUICInfo struct {
+	LongHeader bool
+	PacketType uint8  // QUICInitial...QUICRetry, long headers only
+	Version    uint32 // Long headers only
+	DCID       string
+	SCID       string
+}
+
+// QUICVersionName returns the name of a QUIC version, e.g. "QUIC v1".
+func QUICVersionName(v uint32) string {
+	switch {
+	case v == QUICVersion1:
+		return "QUIC v1"
+	case v == QUICVersion2:
+		return "QUIC v2"
+	case v == QUICVersionNegotiation:
+		return "Version Negotiation"
+	case v&0x0f0f0f0f == 0x0a0a0a0a:
+		return "GREASE"
+	case v>>8 == 0xff0000:
+		return fmt.Sprintf("draft-%d", v&0xff)
+	}
+	return fmt.Sprintf("0x%08x", v)
+}
@@ -0,0 +1,33 @@
+package models
+
+// TCP header flags.
+const (
+	TCPFlagFIN uint16 = 0x01
+	TCPFlagSYN uint16 = 0x02
+	TCPFlagRST uint16 = 0x04
+	TCPFlagPSH uint16 = 0x08
+	TCPFlagACK uint16 = 0x10
+	TCPFlagURG uint16 = 0x20
+	TCPFlagECE uint16 = 0x40
+	TCPFlagCWR uint16 = 0x80
+)
+
+// TCPInfo is the TCP header state of a packet, with the sequence analysis
+// results of the backend.
+type TCPInfo struct {
+	Flags          uint16 // TCPFlag bits
+	Window         int    // Advertised window, before scaling
+	Retransmission bool   // The segment repeats data already sent
+	DuplicateAck   bool   // The segment acknowledges the same data as the previous one
+	ZeroWindow     bool   // The sender has no receive buffer left
+
+	// SampledAnalysis is set when Retransmission and DuplicateAck were judged
+	// from the sampled packets only, which rarely include consecutive
+	// segments of a connection.
+	SampledAnalysis bool
+}
+
+// Has reports whether all the given flags are set.
+func (t *TCPInfo) Has(flags uint16) bool {
+	return t.Flags&flags == flags
+}
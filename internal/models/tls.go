@@ -0,0 +1,42 @@
+package models
+
+import "fmt"
+
+// TLS handshake message types of interest.
+const (
+	TLSClientHello = 1
+	TLSServerHello = 2
+)
+
+// TLSInfo is the TLS handshake information carried by a packet.
+// It is only set on packets holding a ClientHello or a ServerHello.
+type TLSInfo struct {
+	HandshakeType uint8    // TLSClientHello or TLSServerHello
+	ServerName    string   // SNI of a ClientHello
+	ALPN          []string // Protocols offered by a ClientHello or selected by a ServerHello
+	Version       uint16   // Highest version offered by a ClientHello, or selected by a ServerHello
+	JA3           string   // JA3 fingerprint (MD5) of a ClientHello
+	JA4           string   // JA4 fingerprint of a ClientHello, if the backend computes it
+}
+
+var tlsVersionNames = map[uint16]string{
+	0x0300: "SSL 3.0",
+	0x0301: "TLS 1.0",
+	0x0302: "TLS 1.1",
+	0x0303: "TLS 1.2",
+	0x0304: "TLS 1.3",
+}
+
+// TLSVersionName returns the name of a TLS protocol version, e.g. "TLS 1.3".
+func TLSVersionName(v uint16) string {
+	if name, ok := tlsVersionNames[v]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", v)
+}
+
+// IsGREASE reports whether a TLS code point is a GREASE value (RFC 8701),
+// which clients send at random and fingerprints ignore.
+func IsGREASE(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+}
@@ -0,0 +1,36 @@
+package models
+
+// Tunnel encapsulations decoded by the backends.
+const (
+	TunnelGRE    = "GRE"
+	TunnelVXLAN  = "VXLAN"
+	TunnelGENEVE = "GENEVE"
+)
+
+// TunnelInfo describes the encapsulation of a tunneled packet and the
+// addressing of the packet it carries. The PacketData addressing fields
+// keep the outer headers.
+type TunnelInfo struct {
+	Type     string // TunnelGRE, TunnelVXLAN or TunnelGENEVE
+	VNI      uint32 // VXLAN or GENEVE network identifier, or GRE key (0 if absent)
+	Family   AddressFamily
+	SrcIP    string
+	DstIP    string
+	SrcPort  int
+	DstPort  int
+	Protocol string // Transport-level label of the inner packet
+}
+
+// Inner returns the packet with its addressing replaced by that of the
+// tunneled packet it carries. Packets that are not tunneled are returned as is.
+func (p PacketData) Inner() PacketData {
+	t := p.Tunnel
+	if t == nil {
+		return p
+	}
+	p.Family = t.Family
+	p.SrcIP, p.DstIP = t.SrcIP, t.DstIP
+	p.SrcPort, p.DstPort = t.SrcPort, t.DstPort
+	p.Protocol = t.Protocol
+	return p
+}
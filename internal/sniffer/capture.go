@@ -0,0 +1,352 @@
+package sniffer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gonetwatch/internal/capture"
+	"gonetwatch/internal/models"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+)
+
+// readTimeout bounds how long a live read may block, so Stop is noticed promptly.
+const readTimeout = 500 * time.Millisecond
+
+func init() {
+	capture.Register("native", NewSource)
+}
+
+// Source captures packets with libpcap and decodes them in-process with gopacket,
+// without spawning tshark.
+type Source struct {
+	cfg      capture.Config
+	handles  []*namedHandle
+	ring     *ringWriter      // nil unless a ring buffer was requested
+	sampler  *capture.Sampler // nil unless sampling
+	out      chan models.PacketData
+	errs     chan error
+	stop     chan struct{}
+	once     sync.Once
+	wg       sync.WaitGroup
+	packets  atomic.Int64
+	pipeDrop atomic.Int64
+	skipped  atomic.Int64
+	sampled  atomic.Int64
+
+	handlesMu   sync.Mutex // Guards the handles once started, and their drop counters
+	closedDrops int64      // Drops counted on handles that have been closed
+}
+
+// namedHandle is an open pcap handle and the interface it captures from.
+// The name is empty when reading a file.
+type namedHandle struct {
+	name   string
+	index  int // Position among the handles, used as the pcapng interface
+	handle *pcap.Handle
+	drops  int64 // Last kernel and interface drop count read from the handle
+}
+
+// NewSource creates a native source from a capture configuration.
+func NewSource(cfg capture.Config) (capture.Source, error) {
+	if len(cfg.Interfaces) == 0 && cfg.File == "" {
+		return nil, errors.New("native backend needs an interface or a capture file")
+	}
+	if len(cfg.ExtraFields) > 0 {
+		return nil, errors.New("extra fields are dissected by tshark and need the tshark backend")
+	}
+	if cfg.Ring.Enabled() && cfg.File != "" {
+		return nil, errors.New("the ring buffer only applies to live captures")
+	}
+	if cfg.OutputFormat != "" {
+		return nil, errors.New("output formats only apply to the tshark backend")
+	}
+	if cfg.DisplayFilter != "" {
+		return nil, errors.New("display filters (-Y) need the tshark backend; use a capture filter (-f)")
+	}
+
+	return &Source{
+		cfg:     cfg,
+		sampler: capture.NewSampler(cfg.Sampling),
+		out:     make(chan models.PacketData, 1000),
+		errs:    make(chan error, 16),
+		stop:    make(chan struct{}),
+	}, nil
+}
+
+// Start opens one pcap handle per interface (or one for the capture file)
+// and begins decoding packets in the background.
+func (s *Source) Start(ctx context.Context) error {
+	if s.cfg.File != "" {
+		handle, err := pcap.OpenOffline(s.cfg.File)
+		if err != nil {
+			return fmt.Errorf("failed to open capture file: %v", err)
+		}
+		s.handles = append(s.handles, &namedHandle{handle: handle})
+	}
+	for _, iface := range s.cfg.Interfaces {
+		handle, err := pcap.OpenLive(iface, 65536, true, readTimeout)
+		if err != nil {
+			s.closeHandles()
+			return fmt.Errorf("failed to open pcap handle on %s: %v", iface, err)
+		}
+		s.handles = append(s.handles, &namedHandle{name: iface, index: len(s.handles), handle: handle})
+	}
+
+	if s.cfg.CaptureFilter != "" {
+		for _, h := range s.handles {
+			if err := h.handle.SetBPFFilter(s.cfg.CaptureFilter); err != nil {
+				s.closeHandles()
+				return fmt.Errorf("invalid capture filter %q: %v", s.cfg.CaptureFilter, err)
+			}
+		}
+	}
+
+	if s.cfg.Ring.Enabled() {
+		ring, err := newRingWriter(s.cfg.Ring, s.handles)
+		if err != nil {
+			s.closeHandles()
+			return err
+		}
+		s.ring = ring
+	}
+
+	for _, h := range s.handles {
+		s.wg.Add(1)
+		go s.readLoop(h)
+	}
+
+	// The packet channel is closed once every handle is exhausted or stopped
+	go func() {
+		s.wg.Wait()
+		close(s.out)
+	}()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.Stop()
+		case <-s.stop:
+		}
+	}()
+	return nil
+}
+
+func (s *Source) readLoop(h *namedHandle) {
+	defer s.wg.Done()
+
+	dec := newDecoder(h.handle.LinkType())
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+
+		data, ci, err := h.handle.ZeroCopyReadPacketData()
+		if err != nil {
+			if errors.Is(err, pcap.NextErrorTimeoutExpired) {
+				continue
+			}
+			if errors.Is(err, io.EOF) || errors.Is(err, pcap.NextErrorNoMorePackets) {
+				return
+			}
+			label := h.name
+			if label == "" {
+				label = s.cfg.File
+			}
+			s.reportError(fmt.Errorf("pcap read failed on %s: %v", label, err))
+			return
+		}
+
+		// The data is only valid until the next read, so it is saved first
+		if s.ring != nil {
+			if err := s.ring.write(h.index, ci, data); err != nil {
+				s.ring.close()
+				s.reportError(fmt.Errorf("ring buffer stopped: %v", err))
+			}
+		}
+
+		// The ring buffer keeps every packet; sampling only spares the decoding
+		if !s.sampler.Keep() {
+			s.sampled.Add(1)
+			continue
+		}
+
+		pkt, ok := dec.decode(data, ci)
+		if !ok {
+			s.skipped.Add(1)
+			continue
+		}
+		pkt.Interface = h.name
+		// The sequence analysis only followed the packets kept by sampling
+		if s.sampler != nil && pkt.TCP != nil {
+			pkt.TCP.SampledAnalysis = true
+		}
+
+		if !s.deliver(pkt) {
+			return
+		}
+	}
+}
+
+// deliver hands a packet to the consumer and reports false once the source is stopped.
+// Live captures drop and count packets the consumer has no room for rather than
+// stalling the read loop, which would only move the loss into the kernel buffer.
+func (s *Source) deliver(pkt models.PacketData) bool {
+	if s.cfg.File == "" {
+		select {
+		case s.out <- pkt:
+			s.packets.Add(1)
+		case <-s.stop:
+			return false
+		default:
+			s.pipeDrop.Add(1)
+		}
+		return true
+	}
+
+	select {
+	case s.out <- pkt:
+		s.packets.Add(1)
+		return true
+	case <-s.stop:
+		return false
+	}
+}
+
+// Validate compiles the capture filter so syntax errors surface before the capture starts.
+func (s *Source) Validate() error {
+	if s.cfg.CaptureFilter == "" {
+		return nil
+	}
+	if _, err := pcap.CompileBPFFilter(layers.LinkTypeEthernet, 65536, s.cfg.CaptureFilter); err != nil {
+		return fmt.Errorf("invalid capture filter %q: %v", s.cfg.CaptureFilter, err)
+	}
+	return nil
+}
+
+// SetFilters replaces the capture filter on the open handles.
+// Display filters are not supported by the native backend.
+// The filter is compiled for every handle before any of them is changed, so
+// an invalid filter leaves the capture as it was.
+func (s *Source) SetFilters(captureFilter, displayFilter string) error {
+	if displayFilter != "" {
+		return errors.New("display filters need the tshark backend")
+	}
+
+	// Held throughout so that Stop cannot close the handles meanwhile
+	s.handlesMu.Lock()
+	defer s.handlesMu.Unlock()
+
+	if len(s.handles) == 0 {
+		return errors.New("capture is not running")
+	}
+	programs := make([][]pcap.BPFInstruction, len(s.handles))
+	for i, h := range s.handles {
+		bpf, err := pcap.CompileBPFFilter(h.handle.LinkType(), h.handle.SnapLen(), captureFilter)
+		if err != nil {
+			return fmt.Errorf("invalid capture filter %q: %v", captureFilter, err)
+		}
+		programs[i] = bpf
+	}
+
+	for i, h := range s.handles {
+		if err := h.handle.SetBPFInstructionFilter(programs[i]); err != nil {
+			// Put the handles already changed back on the previous filter
+			for _, prev := range s.handles[:i] {
+				_ = prev.handle.SetBPFFilter(s.cfg.CaptureFilter)
+			}
+			return fmt.Errorf("failed to apply capture filter %q: %v", captureFilter, err)
+		}
+	}
+	s.cfg.CaptureFilter = captureFilter
+	return nil
+}
+
+// Stop halts the read loops and closes the pcap handles.
+func (s *Source) Stop() error {
+	s.once.Do(func() {
+		close(s.stop)
+		s.wg.Wait()
+		if s.ring != nil {
+			if err := s.ring.close(); err != nil {
+				s.reportError(err)
+			}
+		}
+		s.closeHandles()
+	})
+	return nil
+}
+
+func (s *Source) closeHandles() {
+	s.handlesMu.Lock()
+	defer s.handlesMu.Unlock()
+
+	for _, h := range s.handles {
+		readDrops(h)
+		s.closedDrops += h.drops
+		h.handle.Close()
+	}
+	s.handles = nil
+}
+
+// kernelDrops returns the packets libpcap reports as dropped on all handles.
+// Counts are remembered so they stay available after the handles are closed.
+func (s *Source) kernelDrops() int64 {
+	s.handlesMu.Lock()
+	defer s.handlesMu.Unlock()
+
+	total := s.closedDrops
+	for _, h := range s.handles {
+		readDrops(h)
+		total += h.drops
+	}
+	return total
+}
+
+// readDrops refreshes the drop count of a live handle. Offline handles have no
+// statistics and keep a count of zero.
+func readDrops(h *namedHandle) {
+	if h.name == "" {
+		return
+	}
+	if st, err := h.handle.Stats(); err == nil {
+		h.drops = int64(st.PacketsDropped + st.PacketsIfDropped)
+	}
+}
+
+// Packets returns the channel of decoded packets.
+func (s *Source) Packets() <-chan models.PacketData { return s.out }
+
+// Errors returns the channel of capture errors.
+func (s *Source) Errors() <-chan error { return s.errs }
+
+// Stats returns the capture counters.
+func (s *Source) Stats() capture.Stats {
+	st := capture.Stats{
+		Packets:       s.packets.Load(),
+		KernelDrops:   s.kernelDrops(),
+		PipelineDrops: s.pipeDrop.Load(),
+		Skipped:       s.skipped.Load(),
+		SampledOut:    s.sampled.Load(),
+	}
+	if s.ring != nil {
+		st.RingFile = s.ring.current()
+	}
+	return st
+}
+
+// reportError forwards an error without ever blocking the capture loop.
+func (s *Source) reportError(err error) {
+	select {
+	case s.errs <- err:
+	default:
+	}
+}
@@ -0,0 +1,261 @@
+package sniffer
+
+import (
+	"gonetwatch/internal/models"
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// decoder turns raw frames into PacketData using a preallocated
+// DecodingLayerParser, which avoids allocating a gopacket.Packet per frame.
+type decoder struct {
+	parser  *gopacket.DecodingLayerParser
+	parser6 *gopacket.DecodingLayerParser // used for IPv6 packets on raw IP links
+	decoded []gopacket.LayerType
+
+	eth     layers.Ethernet
+	sll     layers.LinuxSLL
+	loop    layers.Loopback
+	vlan    layers.Dot1Q
+	ip4     layers.IPv4
+	ip6     layers.IPv6
+	tcp     layers.TCP
+	udp     layers.UDP
+	icmp    layers.ICMPv4
+	icmp6   layers.ICMPv6
+	dns     layers.DNS
+	dhcp    layers.DHCPv4
+	mdns    layers.DNS // mDNS and LLMNR, decoded by hostNames
+	payload gopacket.Payload
+
+	// Outer headers of IP-in-IP packets, see outerIPv4 and outerIPv6
+	outer4 layers.IPv4
+	outer6 layers.IPv6
+
+	tcpState *tcpAnalyzer
+	quicIDs  *quicConnIDs
+
+	// Decoders for the packets carried by tunnels, created on first use
+	nested   bool // Set on the inner decoders, which do not follow further tunnels
+	innerEth *decoder
+	innerIP  *decoder
+}
+
+// dissectorNames maps gopacket layer types to the names tshark uses in
+// frame.protocols, so both backends produce the same protocol chains.
+var dissectorNames = map[gopacket.LayerType]string{
+	layers.LayerTypeEthernet: "eth",
+	layers.LayerTypeLinuxSLL: "sll",
+	layers.LayerTypeLoopback: "null",
+	layers.LayerTypeDot1Q:    "vlan",
+	layers.LayerTypeIPv4:     "ip",
+	layers.LayerTypeIPv6:     "ipv6",
+	layers.LayerTypeTCP:      "tcp",
+	layers.LayerTypeUDP:      "udp",
+	layers.LayerTypeICMPv4:   "icmp",
+	layers.LayerTypeICMPv6:   "icmpv6",
+	layers.LayerTypeDNS:      "dns",
+	layers.LayerTypeDHCPv4:   "dhcp",
+}
+
+func newDecoder(linkType layers.LinkType) *decoder {
+	d := &decoder{tcpState: newTCPAnalyzer(), quicIDs: newQUICConnIDs()}
+
+	first := layers.LayerTypeEthernet
+	switch linkType {
+	case layers.LinkTypeLinuxSLL:
+		first = layers.LayerTypeLinuxSLL
+	case layers.LinkTypeNull, layers.LinkTypeLoop:
+		first = layers.LayerTypeLoopback
+	case layers.LinkTypeRaw, layers.LinkTypeIPv4, layers.LinkTypeIPv6:
+		// Raw IP captures carry no link header; the IP version is picked per packet.
+		first = layers.LayerTypeIPv4
+		d.parser6 = d.newParser(layers.LayerTypeIPv6)
+	}
+
+	d.parser = d.newParser(first)
+	return d
+}
+
+func (d *decoder) newParser(first gopacket.LayerType) *gopacket.DecodingLayerParser {
+	parser := gopacket.NewDecodingLayerParser(first,
+		&d.eth, &d.sll, &d.loop, &d.vlan,
+		&d.ip4, &d.ip6,
+		&d.tcp, &d.udp, &d.icmp, &d.icmp6,
+		&d.dns, &d.dhcp,
+		&d.payload,
+	)
+	parser.IgnoreUnsupported = true
+	return parser
+}
+
+// decode extracts the packet fields. It reports false if not even the link layer could be decoded.
+func (d *decoder) decode(data []byte, ci gopacket.CaptureInfo) (models.PacketData, bool) {
+	parser := d.parser
+	if d.parser6 != nil && len(data) > 0 && data[0]>>4 == 6 {
+		parser = d.parser6
+	}
+
+	// Errors only mean that decoding stopped early; whatever was decoded is still usable.
+	_ = parser.DecodeLayers(data, &d.decoded)
+
+	p := models.PacketData{
+		Timestamp: ci.Timestamp,
+		Length:    ci.Length,
+		Protocol:  "OTHER",
+	}
+
+	hasIP := false
+	linkPayload := data // Where the first IP header starts
+	for _, lt := range d.decoded {
+		if name, ok := dissectorNames[lt]; ok {
+			p.Protocols = append(p.Protocols, name)
+		}
+
+		switch lt {
+		case layers.LayerTypeEthernet:
+			p.SrcMAC = d.eth.SrcMAC.String()
+			p.DstMAC = d.eth.DstMAC.String()
+			p.EtherType = uint16(d.eth.EthernetType)
+			linkPayload = d.eth.LayerPayload()
+			if d.eth.EthernetType == layers.EthernetTypeDot1Q || d.eth.EthernetType == layers.EthernetTypeQinQ {
+				p.VLANs = vlanIDs(data)
+			}
+		case layers.LayerTypeLinuxSLL:
+			p.SrcMAC = net.HardwareAddr(d.sll.Addr).String()
+			p.EtherType = uint16(d.sll.EthernetType)
+			linkPayload = d.sll.LayerPayload()
+		case layers.LayerTypeLoopback:
+			linkPayload = d.loop.LayerPayload()
+		case layers.LayerTypeDot1Q:
+			p.EtherType = uint16(d.vlan.Type)
+			linkPayload = d.vlan.LayerPayload()
+		case layers.LayerTypeIPv4:
+			if hasIP {
+				break // keep the outer header of tunneled packets
+			}
+			hasIP = true
+			ip := d.outerIPv4(linkPayload)
+			p.Family = models.FamilyIPv4
+			p.SrcIP = ipString(ip.SrcIP)
+			p.DstIP = ipString(ip.DstIP)
+		case layers.LayerTypeIPv6:
+			if hasIP {
+				break // keep the outer header of tunneled packets
+			}
+			hasIP = true
+			ip := d.outerIPv6(linkPayload)
+			p.Family = models.FamilyIPv6
+			p.SrcIP = ipString(ip.SrcIP)
+			p.DstIP = ipString(ip.DstIP)
+		case layers.LayerTypeTCP:
+			p.Protocol = "TCP"
+			p.SrcPort = int(d.tcp.SrcPort)
+			p.DstPort = int(d.tcp.DstPort)
+			p.TCP = d.tcpState.analyze(&d.tcp, p.SrcIP, p.DstIP, ci.Timestamp)
+			if payload := d.tcp.LayerPayload(); isTLSRecord(payload) {
+				p.Protocols = append(p.Protocols, "tls")
+				p.TLS = parseHello(payload)
+			} else if info := parseHTTP(payload); info != nil {
+				p.Protocols = append(p.Protocols, "http")
+				p.HTTP = info
+			}
+		case layers.LayerTypeUDP:
+			p.Protocol = "UDP"
+			p.SrcPort = int(d.udp.SrcPort)
+			p.DstPort = int(d.udp.DstPort)
+			if p.SrcPort == quicPort || p.DstPort == quicPort {
+				if info := d.quicIDs.parseQUIC(d.udp.LayerPayload(), ci.Timestamp); info != nil {
+					p.Protocols = append(p.Protocols, "quic")
+					p.QUIC = info
+				}
+			}
+			p.Names = d.hostNames(&p)
+		case layers.LayerTypeICMPv4:
+			p.Protocol = "ICMP"
+			p.ICMP = icmpv4Info(&d.icmp)
+		case layers.LayerTypeICMPv6:
+			p.Protocol = "ICMPv6"
+			p.ICMP = icmpv6Info(&d.icmp6)
+		case layers.LayerTypeDNS:
+			p.DNS = dnsInfo(&d.dns)
+		case layers.LayerTypeDHCPv4:
+			p.Names = dhcpNames(&d.dhcp)
+		}
+	}
+
+	if len(d.decoded) == 0 {
+		return p, false
+	}
+	if !hasIP {
+		// Non-IP frames are named from their EtherType during analysis
+		p.Protocol = ""
+		return p, true
+	}
+	d.decodeTunnel(&p, ci)
+	return p, true
+}
+
+// outerIPv4 returns the first IPv4 header of the frame. An IPv4-in-IPv4
+// packet is decoded twice into the same reused layer, which then holds the
+// inner header, so the outer one is decoded again from the frame.
+func (d *decoder) outerIPv4(header []byte) *layers.IPv4 {
+	if decodedTwice(d.decoded, layers.LayerTypeIPv4) && d.outer4.DecodeFromBytes(header, gopacket.NilDecodeFeedback) == nil {
+		return &d.outer4
+	}
+	return &d.ip4
+}
+
+// outerIPv6 is outerIPv4 for IPv6-in-IPv6 packets.
+func (d *decoder) outerIPv6(header []byte) *layers.IPv6 {
+	if decodedTwice(d.decoded, layers.LayerTypeIPv6) && d.outer6.DecodeFromBytes(header, gopacket.NilDecodeFeedback) == nil {
+		return &d.outer6
+	}
+	return &d.ip6
+}
+
+// decodedTwice reports whether a layer type occurs more than once in a frame.
+func decodedTwice(decoded []gopacket.LayerType, lt gopacket.LayerType) bool {
+	seen := false
+	for _, t := range decoded {
+		if t == lt {
+			if seen {
+				return true
+			}
+			seen = true
+		}
+	}
+	return false
+}
+
+// dnsInfo copies the fields of a decoded DNS message. The layer is reused
+// for the next packet, so nothing may reference its buffers.
+func dnsInfo(dns *layers.DNS) *models.DNSInfo {
+	info := &models.DNSInfo{
+		ID:       dns.ID,
+		Response: dns.QR,
+		RCode:    int(dns.ResponseCode),
+	}
+	if len(dns.Questions) > 0 {
+		info.QueryName = string(dns.Questions[0].Name)
+		info.QueryType = uint16(dns.Questions[0].Type)
+	}
+	for _, a := range dns.Answers {
+		switch a.Type {
+		case layers.DNSTypeA, layers.DNSTypeAAAA:
+			info.Answers = append(info.Answers, ipString(a.IP))
+		case layers.DNSTypeCNAME:
+			info.Answers = append(info.Answers, string(a.CNAME))
+		}
+	}
+	return info
+}
+
+func ipString(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
@@ -0,0 +1,67 @@
+package sniffer
+
+import (
+	"bytes"
+	"gonetwatch/internal/models"
+	"strconv"
+	"strings"
+)
+
+// httpMethods are the request methods recognised at the start of a TCP payload.
+var httpMethods = []string{
+	"GET", "POST", "PUT", "DELETE", "HEAD", "OPTIONS", "PATCH", "CONNECT", "TRACE",
+}
+
+// parseHTTP reads the start line and headers of an HTTP/1.x request or response
+// at the start of a TCP payload. It returns nil for anything else, including
+// continuation segments of a body.
+func parseHTTP(payload []byte) *models.HTTPInfo {
+	// Only the header block matters; it normally fits in the first segment
+	if end := bytes.Index(payload, []byte("\r\n\r\n")); end >= 0 {
+		payload = payload[:end]
+	}
+	lines := strings.Split(string(payload), "\r\n")
+
+	first := strings.Fields(lines[0])
+	if len(first) < 2 {
+		return nil
+	}
+
+	if strings.HasPrefix(first[0], "HTTP/1.") {
+		code, err := strconv.Atoi(first[1])
+		if err != nil || code < 100 || code > 999 {
+			return nil
+		}
+		return &models.HTTPInfo{StatusCode: code}
+	}
+
+	if len(first) != 3 || !strings.HasPrefix(first[2], "HTTP/1.") || !isHTTPMethod(first[0]) {
+		return nil
+	}
+	info := &models.HTTPInfo{Request: true, Method: first[0]}
+	info.Path, _, _ = strings.Cut(first[1], "?")
+
+	for _, line := range lines[1:] {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(name) {
+		case "host":
+			info.Host = value
+		case "user-agent":
+			info.UserAgent = value
+		}
+	}
+	return info
+}
+
+func isHTTPMethod(s string) bool {
+	for _, m := range httpMethods {
+		if s == m {
+			return true
+		}
+	}
+	return false
+}
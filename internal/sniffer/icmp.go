@@ -0,0 +1,47 @@
+package sniffer
+
+import (
+	"encoding/binary"
+	"gonetwatch/internal/models"
+
+	"github.com/google/gopacket/layers"
+)
+
+// icmpv4Info copies the fields of a decoded ICMP header.
+func icmpv4Info(icmp *layers.ICMPv4) *models.ICMPInfo {
+	info := &models.ICMPInfo{
+		Type: icmp.TypeCode.Type(),
+		Code: icmp.TypeCode.Code(),
+	}
+	switch info.Kind() {
+	case models.ICMPEchoRequest, models.ICMPEchoReply:
+		info.ID = icmp.Id
+		info.Seq = icmp.Seq
+	case models.ICMPPacketTooBig:
+		// Fragmentation Needed carries the next-hop MTU where echoes carry the sequence number
+		info.MTU = int(icmp.Seq)
+	}
+	return info
+}
+
+// icmpv6Info copies the fields of a decoded ICMPv6 header. gopacket leaves
+// everything after the type, code and checksum in the payload.
+func icmpv6Info(icmp *layers.ICMPv6) *models.ICMPInfo {
+	info := &models.ICMPInfo{
+		V6:   true,
+		Type: icmp.TypeCode.Type(),
+		Code: icmp.TypeCode.Code(),
+	}
+	body := icmp.LayerPayload()
+	if len(body) < 4 {
+		return info
+	}
+	switch info.Kind() {
+	case models.ICMPEchoRequest, models.ICMPEchoReply:
+		info.ID = binary.BigEndian.Uint16(body[0:2])
+		info.Seq = binary.BigEndian.Uint16(body[2:4])
+	case models.ICMPPacketTooBig:
+		info.MTU = int(binary.BigEndian.Uint32(body[0:4]))
+	}
+	return info
+}
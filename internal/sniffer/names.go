@@ -0,0 +1,224 @@
+package sniffer
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"gonetwatch/internal/models"
+	"strings"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Ports of the name services hosts announce themselves with. DHCP is mapped
+// to its layer by gopacket.
+const (
+	nbnsPort  = 137
+	mdnsPort  = 5353
+	llmnrPort = 5355
+)
+
+// NetBIOS name service opcodes and record type.
+const (
+	nbnsOpQuery        = 0
+	nbnsOpRegistration = 5
+	nbnsOpRefresh      = 8
+	nbnsOpMultiHomed   = 9
+	nbnsTypeNB         = 0x20
+)
+
+// hostNames decodes the name announcements of mDNS, LLMNR and the NetBIOS
+// name service, adding the protocol to the dissection chain. gopacket does
+// not map their ports, so the UDP payload is decoded here.
+func (d *decoder) hostNames(p *models.PacketData) []models.HostName {
+	payload := d.udp.LayerPayload()
+	switch {
+	case p.SrcPort == mdnsPort || p.DstPort == mdnsPort:
+		if d.mdns.DecodeFromBytes(payload, gopacket.NilDecodeFeedback) != nil {
+			return nil
+		}
+		p.Protocols = append(p.Protocols, "mdns")
+		return dnsNames(&d.mdns, models.NameMDNS)
+	case p.SrcPort == llmnrPort || p.DstPort == llmnrPort:
+		if d.mdns.DecodeFromBytes(payload, gopacket.NilDecodeFeedback) != nil {
+			return nil
+		}
+		p.Protocols = append(p.Protocols, "llmnr")
+		return dnsNames(&d.mdns, models.NameLLMNR)
+	case p.SrcPort == nbnsPort || p.DstPort == nbnsPort:
+		names, ok := parseNBNS(payload)
+		if ok {
+			p.Protocols = append(p.Protocols, "nbns")
+		}
+		return names
+	}
+	return nil
+}
+
+// dnsNames returns the addresses an mDNS or LLMNR response gives for host
+// names. mDNS announcements also put addresses in the additional records.
+func dnsNames(dns *layers.DNS, source models.NameSource) []models.HostName {
+	if !dns.QR {
+		return nil
+	}
+	var names []models.HostName
+	for _, records := range [][]layers.DNSResourceRecord{dns.Answers, dns.Additionals} {
+		for _, rr := range records {
+			if rr.Type != layers.DNSTypeA && rr.Type != layers.DNSTypeAAAA {
+				continue
+			}
+			name := strings.TrimSuffix(strings.TrimSuffix(string(rr.Name), "."), ".local")
+			if name == "" || rr.IP == nil {
+				continue
+			}
+			names = append(names, models.HostName{Source: source, Name: name, IP: ipString(rr.IP)})
+		}
+	}
+	return names
+}
+
+// dhcpNames returns the host name and client identifier of a DHCP client
+// message, or the address a DHCP ACK binds to the client's MAC.
+func dhcpNames(dhcp *layers.DHCPv4) []models.HostName {
+	h := models.HostName{Source: models.NameDHCP}
+	if dhcp.HardwareType == layers.LinkTypeEthernet && len(dhcp.ClientHWAddr) == 6 {
+		h.MAC = dhcp.ClientHWAddr.String()
+	}
+
+	var msgType layers.DHCPMsgType
+	for _, opt := range dhcp.Options {
+		switch opt.Type {
+		case layers.DHCPOptMessageType:
+			if len(opt.Data) == 1 {
+				msgType = layers.DHCPMsgType(opt.Data[0])
+			}
+		case layers.DHCPOptHostname:
+			h.Name = strings.TrimRight(string(opt.Data), "\x00")
+		case layers.DHCPOptClientID:
+			h.ClientID = hex.EncodeToString(opt.Data)
+		}
+	}
+
+	switch msgType {
+	case layers.DHCPMsgTypeDiscover, layers.DHCPMsgTypeRequest, layers.DHCPMsgTypeInform:
+		// Only a client renewing or informing already holds its address
+		if !dhcp.ClientIP.IsUnspecified() {
+			h.IP = ipString(dhcp.ClientIP)
+		}
+	case layers.DHCPMsgTypeAck:
+		h.IP = ipString(dhcp.YourClientIP)
+		if dhcp.YourClientIP.IsUnspecified() {
+			h.IP = ipString(dhcp.ClientIP) // ACK to an INFORM
+		}
+	default:
+		return nil
+	}
+	if h.MAC == "" || (h.Name == "" && h.IP == "" && h.ClientID == "") {
+		return nil
+	}
+	return []models.HostName{h}
+}
+
+// parseNBNS returns the unique names a NetBIOS name service packet binds to
+// addresses: those of registrations and refreshes, and of positive answers to
+// name queries. Group names (workgroups, domains) are left out, as are names
+// other than the workstation and server service. It reports false if the
+// payload is not a name service packet.
+func parseNBNS(b []byte) ([]models.HostName, bool) {
+	if len(b) < 12 {
+		return nil, false
+	}
+	flags := binary.BigEndian.Uint16(b[2:4])
+	response := flags&0x8000 != 0
+	opcode := (flags >> 11) & 0xf
+	rcode := flags & 0xf
+	questions := int(binary.BigEndian.Uint16(b[4:6]))
+	records := 0
+	for i := 6; i < 12; i += 2 {
+		records += int(binary.BigEndian.Uint16(b[i : i+2]))
+	}
+	if questions > 1 || records > 16 {
+		return nil, false
+	}
+
+	off := 12
+	for range questions {
+		_, _, next, ok := nbnsName(b, off)
+		if !ok || next+4 > len(b) {
+			return nil, false
+		}
+		off = next + 4 // Type and class
+	}
+
+	harvest := false
+	switch opcode {
+	case nbnsOpRegistration, nbnsOpRefresh, nbnsOpMultiHomed:
+		harvest = !response
+	case nbnsOpQuery:
+		harvest = response && rcode == 0
+	}
+
+	var names []models.HostName
+	for range records {
+		name, suffix, next, ok := nbnsName(b, off)
+		if !ok || next+10 > len(b) {
+			return nil, false
+		}
+		rtype := binary.BigEndian.Uint16(b[next : next+2])
+		rdlen := int(binary.BigEndian.Uint16(b[next+8 : next+10]))
+		rdata := next + 10
+		if rdata+rdlen > len(b) {
+			return nil, false
+		}
+		off = rdata + rdlen
+
+		if !harvest || rtype != nbnsTypeNB || (suffix != 0x00 && suffix != 0x20) || name == "" || name == "*" {
+			continue
+		}
+		// Each entry holds the NB flags, whose top bit marks a group name, and an IPv4 address
+		for i := rdata; i+6 <= rdata+rdlen; i += 6 {
+			if b[i]&0x80 != 0 {
+				continue
+			}
+			ip := ipString(b[i+2 : i+6])
+			names = append(names, models.HostName{Source: models.NameNBNS, Name: name, IP: ip})
+		}
+	}
+	return names, true
+}
+
+// nbnsName reads the first-level encoded NetBIOS name at off, following a
+// compression pointer if there is one. It returns the name without its
+// padding, the service suffix and the offset past the name.
+func nbnsName(b []byte, off int) (name string, suffix byte, next int, ok bool) {
+	at := off
+	if off+2 <= len(b) && b[off]&0xc0 == 0xc0 {
+		at = int(binary.BigEndian.Uint16(b[off:off+2]) & 0x3fff)
+		next = off + 2
+	}
+	if at+33 > len(b) || b[at] != 32 {
+		return "", 0, 0, false
+	}
+
+	var raw [16]byte
+	for i := range raw {
+		hi, lo := b[at+1+2*i]-'A', b[at+2+2*i]-'A'
+		if hi > 15 || lo > 15 {
+			return "", 0, 0, false
+		}
+		raw[i] = hi<<4 | lo
+	}
+
+	if next == 0 {
+		// Skip the scope labels up to the terminating zero length
+		next = at + 33
+		for next < len(b) && b[next] != 0 {
+			next += int(b[next]) + 1
+		}
+		if next >= len(b) {
+			return "", 0, 0, false
+		}
+		next++
+	}
+	return strings.TrimRight(string(raw[:15]), " "), raw[15], next, true
+}
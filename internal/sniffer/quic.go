@@ -0,0 +1,124 @@
+package sniffer
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"gonetwatch/internal/models"
+	"time"
+)
+
+const (
+	// quicPort is the UDP port QUIC is recognised on.
+	quicPort = 443
+	// maxQUICConnIDs bounds the connection IDs remembered to read short headers.
+	maxQUICConnIDs = 100000
+	// quicIdle is how long a connection ID may stay unused before it can be
+	// forgotten to make room for new ones.
+	quicIdle = 2 * time.Minute
+)
+
+// quicConnIDs remembers the connection IDs announced in long headers.
+// Short headers do not encode the length of their destination CID, so it is
+// found by trying the lengths of the IDs seen so far.
+type quicConnIDs struct {
+	lastUsed map[string]time.Time
+	lengths  map[int]int // Number of remembered IDs of each length
+}
+
+func newQUICConnIDs() *quicConnIDs {
+	return &quicConnIDs{lastUsed: make(map[string]time.Time), lengths: make(map[int]int)}
+}
+
+// parseQUIC reads the header of the first QUIC packet of a UDP datagram,
+// or returns nil if the payload is not QUIC. Packets are not decrypted.
+func (c *quicConnIDs) parseQUIC(b []byte, ts time.Time) *models.QUICInfo {
+	// The fixed bit is set in every packet except Version Negotiation
+	if len(b) < 1 {
+		return nil
+	}
+
+	if b[0]&0x80 == 0 {
+		if b[0]&0x40 == 0 {
+			return nil
+		}
+		q := &models.QUICInfo{}
+		for n := range c.lengths {
+			if len(b) >= 1+n {
+				if id := hex.EncodeToString(b[1 : 1+n]); c.known(id, ts) {
+					q.DCID = id
+					break
+				}
+			}
+		}
+		return q
+	}
+
+	// Long header: flags, version, DCID length and DCID, SCID length and SCID
+	if len(b) < 7 {
+		return nil
+	}
+	q := &models.QUICInfo{LongHeader: true, Version: binary.BigEndian.Uint32(b[1:5])}
+	if q.Version != models.QUICVersionNegotiation && b[0]&0x40 == 0 {
+		return nil
+	}
+	q.PacketType = (b[0] >> 4) & 0x03
+	if q.Version == models.QUICVersion2 {
+		// Version 2 numbers the types differently: Retry, Initial, 0-RTT, Handshake
+		q.PacketType = (q.PacketType + 3) & 0x03
+	}
+
+	off := 5
+	dcidLen := int(b[off])
+	if dcidLen > 20 || len(b) < off+1+dcidLen+1 {
+		return nil
+	}
+	q.DCID = hex.EncodeToString(b[off+1 : off+1+dcidLen])
+	off += 1 + dcidLen
+	scidLen := int(b[off])
+	if scidLen > 20 || len(b) < off+1+scidLen {
+		return nil
+	}
+	q.SCID = hex.EncodeToString(b[off+1 : off+1+scidLen])
+
+	c.remember(q.SCID, ts)
+	c.remember(q.DCID, ts)
+	return q
+}
+
+// known reports whether a connection ID was announced, and marks it as used.
+func (c *quicConnIDs) known(id string, ts time.Time) bool {
+	if _, ok := c.lastUsed[id]; !ok {
+		return false
+	}
+	c.lastUsed[id] = ts
+	return true
+}
+
+// remember adds a connection ID, forgetting idle ones if the limit is reached.
+func (c *quicConnIDs) remember(id string, ts time.Time) {
+	if id == "" {
+		return
+	}
+	if _, ok := c.lastUsed[id]; !ok {
+		if len(c.lastUsed) >= maxQUICConnIDs {
+			for k, last := range c.lastUsed {
+				if ts.Sub(last) > quicIdle {
+					c.forget(k)
+				}
+			}
+			if len(c.lastUsed) >= maxQUICConnIDs {
+				return
+			}
+		}
+		c.lengths[len(id)/2]++
+	}
+	c.lastUsed[id] = ts
+}
+
+func (c *quicConnIDs) forget(id string) {
+	delete(c.lastUsed, id)
+	n := len(id) / 2
+	if c.lengths[n]--; c.lengths[n] == 0 {
+		delete(c.lengths, n)
+	}
+}
@@ -0,0 +1,133 @@
+package sniffer
+
+import (
+	"fmt"
+	"gonetwatch/internal/capture"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/pcapgo"
+)
+
+// ringWriter writes captured packets to a rotating set of pcapng files.
+// Every handle is recorded as its own interface, so a multi-interface
+// capture keeps each packet's origin.
+type ringWriter struct {
+	cfg     capture.RingBuffer
+	handles []*namedHandle
+
+	mu      sync.Mutex
+	file    *os.File
+	w       *pcapgo.NgWriter
+	name    string
+	n       int       // Number of the current file
+	size    int64     // Bytes written to the current file
+	started time.Time // When the current file was opened
+}
+
+func newRingWriter(cfg capture.RingBuffer, handles []*namedHandle) (*ringWriter, error) {
+	r := &ringWriter{cfg: cfg, handles: handles}
+	if err := r.rotate(time.Now()); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// write appends a packet read from the handle with the given index,
+// starting a new file first if the current one is full.
+func (r *ringWriter) write(index int, ci gopacket.CaptureInfo, data []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.w == nil {
+		return nil
+	}
+	now := time.Now()
+	if (r.cfg.MaxSize > 0 && r.size >= r.cfg.MaxSize) ||
+		(r.cfg.MaxDuration > 0 && now.Sub(r.started) >= r.cfg.MaxDuration) {
+		if err := r.rotate(now); err != nil {
+			return err
+		}
+	}
+
+	ci.InterfaceIndex = index
+	if err := r.w.WritePacket(ci, data); err != nil {
+		return fmt.Errorf("failed to write %s: %v", r.name, err)
+	}
+	// Enhanced packet block overhead
+	r.size += int64(len(data)) + 32
+	return nil
+}
+
+// rotate closes the current file, opens the next one and prunes old files.
+// Must be called with r.mu held, or before the writer is shared.
+func (r *ringWriter) rotate(now time.Time) error {
+	if err := r.closeFile(); err != nil {
+		return err
+	}
+
+	r.n++
+	name := r.cfg.FileName(r.n, now)
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create ring buffer file: %v", err)
+	}
+
+	w, err := pcapgo.NewNgWriterInterface(f, ngInterface(r.handles[0]), pcapgo.DefaultNgWriterOptions)
+	if err == nil {
+		for _, h := range r.handles[1:] {
+			if _, err = w.AddInterface(ngInterface(h)); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %v", name, err)
+	}
+
+	r.file, r.w, r.name = f, w, name
+	r.size = 0
+	r.started = now
+	return r.cfg.Prune()
+}
+
+// closeFile flushes and closes the current file, if any.
+func (r *ringWriter) closeFile() error {
+	if r.w == nil {
+		return nil
+	}
+	err := r.w.Flush()
+	if cerr := r.file.Close(); err == nil {
+		err = cerr
+	}
+	r.file, r.w = nil, nil
+	if err != nil {
+		return fmt.Errorf("failed to close %s: %v", r.name, err)
+	}
+	return nil
+}
+
+// close finishes the current file. Later writes are ignored.
+func (r *ringWriter) close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closeFile()
+}
+
+// current returns the name of the file being written.
+func (r *ringWriter) current() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.name
+}
+
+func ngInterface(h *namedHandle) pcapgo.NgInterface {
+	intf := pcapgo.DefaultNgInterface
+	intf.Name = h.name
+	intf.LinkType = h.handle.LinkType()
+	intf.SnapLength = uint32(h.handle.SnapLen())
+	return intf
+}
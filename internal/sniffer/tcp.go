@@ -0,0 +1,131 @@
+package sniffer
+
+import (
+	"gonetwatch/internal/models"
+	"time"
+
+	"github.com/google/gopacket/layers"
+)
+
+const (
+	// maxTCPDirections bounds the connection directions whose sequence
+	// numbers are followed.
+	maxTCPDirections = 100000
+	// tcpIdle is how long a direction may stay silent before it can be
+	// forgotten to make room for new ones.
+	tcpIdle = 2 * time.Minute
+)
+
+// tcpAnalyzer follows the sequence and acknowledgement numbers of each
+// direction of a connection to flag retransmissions, duplicate ACKs and
+// zero windows, like tshark's tcp.analysis fields. It sees the packets of
+// a single handle.
+type tcpAnalyzer struct {
+	directions map[tcpDirection]*tcpState
+}
+
+// tcpDirection identifies the packets sent by one side of a connection.
+type tcpDirection struct {
+	src, dst         string
+	srcPort, dstPort layers.TCPPort
+}
+
+type tcpState struct {
+	nextSeq  uint32 // Sequence number following the highest data sent
+	lastAck  uint32
+	window   uint16
+	lastSeen time.Time
+}
+
+func newTCPAnalyzer() *tcpAnalyzer {
+	return &tcpAnalyzer{directions: make(map[tcpDirection]*tcpState)}
+}
+
+// analyze returns the TCP header state of a segment sent from src to dst.
+func (a *tcpAnalyzer) analyze(tcp *layers.TCP, src, dst string, ts time.Time) *models.TCPInfo {
+	info := &models.TCPInfo{Flags: tcpFlags(tcp), Window: int(tcp.Window)}
+
+	// SYN and FIN each take a sequence number, like a byte of data
+	segLen := uint32(len(tcp.LayerPayload()))
+	if tcp.SYN {
+		segLen++
+	}
+	if tcp.FIN {
+		segLen++
+	}
+	control := tcp.SYN || tcp.FIN || tcp.RST
+
+	info.ZeroWindow = tcp.Window == 0 && !control
+
+	key := tcpDirection{src, dst, tcp.SrcPort, tcp.DstPort}
+	st := a.directions[key]
+	if st == nil {
+		if !a.makeRoom(ts) {
+			return info
+		}
+		a.directions[key] = &tcpState{nextSeq: tcp.Seq + segLen, lastAck: tcp.Ack, window: tcp.Window, lastSeen: ts}
+		return info
+	}
+
+	end := tcp.Seq + segLen
+	// Keep-alives resend the byte before nextSeq and are not retransmissions
+	keepAlive := segLen <= 1 && !control && tcp.Seq == st.nextSeq-1
+	if segLen > 0 && !keepAlive && !seqAfter(end, st.nextSeq) {
+		info.Retransmission = true
+	}
+	if segLen == 0 && !control && tcp.ACK && tcp.Ack == st.lastAck && tcp.Window == st.window {
+		info.DuplicateAck = true
+	}
+
+	if seqAfter(end, st.nextSeq) {
+		st.nextSeq = end
+	}
+	if tcp.ACK {
+		st.lastAck = tcp.Ack
+	}
+	st.window = tcp.Window
+	st.lastSeen = ts
+	return info
+}
+
+// makeRoom reports whether another direction can be followed, forgetting
+// idle ones if the limit is reached.
+func (a *tcpAnalyzer) makeRoom(now time.Time) bool {
+	if len(a.directions) < maxTCPDirections {
+		return true
+	}
+	for k, st := range a.directions {
+		if now.Sub(st.lastSeen) > tcpIdle {
+			delete(a.directions, k)
+		}
+	}
+	return len(a.directions) < maxTCPDirections
+}
+
+// seqAfter reports whether sequence number a comes after b, allowing for wrap-around.
+func seqAfter(a, b uint32) bool {
+	return int32(a-b) > 0
+}
+
+// tcpFlags packs the flags of a decoded header into the bits of the header.
+func tcpFlags(tcp *layers.TCP) uint16 {
+	var flags uint16
+	for _, f := range []struct {
+		set bool
+		bit uint16
+	}{
+		{tcp.FIN, models.TCPFlagFIN},
+		{tcp.SYN, models.TCPFlagSYN},
+		{tcp.RST, models.TCPFlagRST},
+		{tcp.PSH, models.TCPFlagPSH},
+		{tcp.ACK, models.TCPFlagACK},
+		{tcp.URG, models.TCPFlagURG},
+		{tcp.ECE, models.TCPFlagECE},
+		{tcp.CWR, models.TCPFlagCWR},
+	} {
+		if f.set {
+			flags |= f.bit
+		}
+	}
+	return flags
+}
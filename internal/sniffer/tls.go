@@ -0,0 +1,234 @@
+package sniffer
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"gonetwatch/internal/models"
+	"strconv"
+	"strings"
+)
+
+// TLS extensions read from hello messages.
+const (
+	extServerName        = 0
+	extSupportedGroups   = 10
+	extECPointFormats    = 11
+	extALPN              = 16
+	extSupportedVersions = 43
+)
+
+// isTLSRecord reports whether a TCP payload starts with a TLS record header:
+// a content type from change_cipher_spec (20) to application_data (23)
+// and major version 3.
+func isTLSRecord(payload []byte) bool {
+	return len(payload) >= 5 && payload[0] >= 20 && payload[0] <= 23 && payload[1] == 3
+}
+
+// parseHello parses a TLS record starting a ClientHello or ServerHello.
+// Messages spread over several TCP segments are not reassembled: as much as
+// the first segment holds is used, and no JA3 is computed for a truncated
+// ClientHello.
+func parseHello(data []byte) *models.TLSInfo {
+	// Record header: content type 22 (handshake), version, length
+	r := tlsReader{b: data}
+	if r.u8() != 22 {
+		return nil
+	}
+	r.skip(4)
+
+	// Handshake header: type, 24-bit length
+	htype := r.u8()
+	length := int(r.u8())<<16 | int(r.u16())
+	if r.err {
+		return nil
+	}
+	complete := len(r.b) >= length
+	if complete {
+		r.b = r.b[:length]
+	}
+
+	switch htype {
+	case models.TLSClientHello:
+		return parseClientHello(r, complete)
+	case models.TLSServerHello:
+		return parseServerHello(r)
+	}
+	return nil
+}
+
+func parseClientHello(r tlsReader, complete bool) *models.TLSInfo {
+	t := &models.TLSInfo{HandshakeType: models.TLSClientHello}
+	legacyVersion := r.u16()
+	r.skip(32) // random
+	r.vec8()   // session id
+	ciphers := r.vec16()
+	r.vec8() // compression methods
+	if r.err {
+		return nil
+	}
+	t.Version = legacyVersion
+
+	var extTypes, groups []uint16
+	var pointFormats []byte
+	// Parse what the segment holds of a truncated extension list
+	exts := tlsReader{b: r.vec16()}
+	if r.err {
+		exts.b, complete = r.remaining(), false
+	}
+	for len(exts.b) > 0 {
+		extType := exts.u16()
+		ext := tlsReader{b: exts.vec16()}
+		if exts.err {
+			complete = false
+			break
+		}
+		extTypes = append(extTypes, extType)
+
+		switch extType {
+		case extServerName:
+			list := tlsReader{b: ext.vec16()}
+			for len(list.b) > 0 && !list.err {
+				nameType, name := list.u8(), list.vec16()
+				if nameType == 0 && !list.err {
+					t.ServerName = string(name)
+				}
+			}
+		case extALPN:
+			list := tlsReader{b: ext.vec16()}
+			for len(list.b) > 0 && !list.err {
+				if proto := list.vec8(); !list.err {
+					t.ALPN = append(t.ALPN, string(proto))
+				}
+			}
+		case extSupportedVersions:
+			list := tlsReader{b: ext.vec8()}
+			for len(list.b) > 1 {
+				if v := list.u16(); !models.IsGREASE(v) {
+					t.Version = max(t.Version, v)
+				}
+			}
+		case extSupportedGroups:
+			list := tlsReader{b: ext.vec16()}
+			for len(list.b) > 1 {
+				groups = append(groups, list.u16())
+			}
+		case extECPointFormats:
+			pointFormats = ext.vec8()
+		}
+	}
+
+	if complete {
+		t.JA3 = ja3(legacyVersion, ciphers, extTypes, groups, pointFormats)
+	}
+	return t
+}
+
+func parseServerHello(r tlsReader) *models.TLSInfo {
+	t := &models.TLSInfo{HandshakeType: models.TLSServerHello}
+	t.Version = r.u16()
+	r.skip(32) // random
+	r.vec8()   // session id
+	r.skip(3)  // cipher suite, compression method
+	if r.err {
+		return nil
+	}
+
+	exts := tlsReader{b: r.vec16()}
+	for len(exts.b) > 0 {
+		extType := exts.u16()
+		ext := tlsReader{b: exts.vec16()}
+		if exts.err {
+			break
+		}
+		switch extType {
+		case extSupportedVersions:
+			if v := ext.u16(); !ext.err {
+				t.Version = v
+			}
+		case extALPN:
+			list := tlsReader{b: ext.vec16()}
+			if proto := list.vec8(); !list.err {
+				t.ALPN = []string{string(proto)}
+			}
+		}
+	}
+	return t
+}
+
+// ja3 computes the JA3 fingerprint of a ClientHello: the MD5 of its version,
+// cipher suites, extensions, groups and point formats, GREASE values excluded.
+func ja3(version uint16, ciphers []byte, extTypes, groups []uint16, pointFormats []byte) string {
+	var cipherList []uint16
+	for i := 0; i+1 < len(ciphers); i += 2 {
+		cipherList = append(cipherList, uint16(ciphers[i])<<8|uint16(ciphers[i+1]))
+	}
+	var formatList []uint16
+	for _, f := range pointFormats {
+		formatList = append(formatList, uint16(f))
+	}
+
+	s := strconv.Itoa(int(version)) + "," +
+		ja3List(cipherList) + "," +
+		ja3List(extTypes) + "," +
+		ja3List(groups) + "," +
+		ja3List(formatList)
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// ja3List joins values with dashes, leaving out GREASE values.
+func ja3List(values []uint16) string {
+	var parts []string
+	for _, v := range values {
+		if !models.IsGREASE(v) {
+			parts = append(parts, strconv.Itoa(int(v)))
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// tlsReader reads big-endian TLS structures. Reading past the end sets err
+// and returns zero values, so a parse can be checked once at the end.
+type tlsReader struct {
+	b   []byte
+	err bool
+}
+
+func (r *tlsReader) next(n int) []byte {
+	if r.err || len(r.b) < n {
+		r.err = true
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *tlsReader) skip(n int) { r.next(n) }
+
+// remaining returns the unread bytes, including those a failed read could not consume.
+func (r *tlsReader) remaining() []byte { return r.b }
+
+func (r *tlsReader) u8() uint8 {
+	if v := r.next(1); v != nil {
+		return v[0]
+	}
+	return 0
+}
+
+func (r *tlsReader) u16() uint16 {
+	if v := r.next(2); v != nil {
+		return uint16(v[0])<<8 | uint16(v[1])
+	}
+	return 0
+}
+
+// vec8 reads a vector with a one-byte length prefix.
+func (r *tlsReader) vec8() []byte {
+	return r.next(int(r.u8()))
+}
+
+// vec16 reads a vector with a two-byte length prefix.
+func (r *tlsReader) vec16() []byte {
+	return r.next(int(r.u16()))
+}
@@ -0,0 +1,147 @@
+package sniffer
+
+import (
+	"encoding/binary"
+	"gonetwatch/internal/models"
+	"strings"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// UDP ports of the UDP-based encapsulations.
+const (
+	vxlanPort  = 4789
+	genevePort = 6081
+)
+
+// vlanIDs reads the IDs of the 802.1Q/802.1ad tags of an Ethernet frame.
+// gopacket decodes stacked tags into the same layer, keeping only the last.
+func vlanIDs(frame []byte) []uint16 {
+	var ids []uint16
+	for off := 12; off+4 <= len(frame); off += 4 {
+		switch layers.EthernetType(binary.BigEndian.Uint16(frame[off:])) {
+		case layers.EthernetTypeDot1Q, layers.EthernetTypeQinQ, 0x9100:
+			ids = append(ids, binary.BigEndian.Uint16(frame[off+2:])&0x0fff)
+		default:
+			return ids
+		}
+	}
+	return ids
+}
+
+// tunnelPayload recognises an encapsulation in the payload of the outer
+// headers and returns the tunnel info, the EtherType of the carried packet
+// and the packet itself.
+func (d *decoder) tunnelPayload(p *models.PacketData) (*models.TunnelInfo, layers.EthernetType, []byte) {
+	switch {
+	case p.Protocol == "UDP" && p.DstPort == vxlanPort:
+		return parseVXLAN(d.udp.LayerPayload())
+	case p.Protocol == "UDP" && p.DstPort == genevePort:
+		return parseGENEVE(d.udp.LayerPayload())
+	case p.Family == models.FamilyIPv4 && d.ip4.Protocol == layers.IPProtocolGRE:
+		return parseGRE(d.ip4.LayerPayload())
+	case p.Family == models.FamilyIPv6 && d.ip6.NextHeader == layers.IPProtocolGRE:
+		return parseGRE(d.ip6.LayerPayload())
+	}
+	return nil, 0, nil
+}
+
+// parseVXLAN reads a VXLAN header (RFC 7348), which always carries Ethernet.
+func parseVXLAN(b []byte) (*models.TunnelInfo, layers.EthernetType, []byte) {
+	// Flags with the VNI-valid bit, reserved, 24-bit VNI, reserved
+	if len(b) < 8 || b[0]&0x08 == 0 {
+		return nil, 0, nil
+	}
+	t := &models.TunnelInfo{Type: models.TunnelVXLAN, VNI: uint24(b[4:])}
+	return t, layers.EthernetTypeTransparentEthernetBridging, b[8:]
+}
+
+// parseGENEVE reads a GENEVE header (RFC 8926) and skips its options.
+func parseGENEVE(b []byte) (*models.TunnelInfo, layers.EthernetType, []byte) {
+	// Version and option length, flags, protocol type, 24-bit VNI, reserved, options
+	if len(b) < 8 || b[0]>>6 != 0 {
+		return nil, 0, nil
+	}
+	end := 8 + int(b[0]&0x3f)*4
+	if len(b) < end {
+		return nil, 0, nil
+	}
+	t := &models.TunnelInfo{Type: models.TunnelGENEVE, VNI: uint24(b[4:])}
+	return t, layers.EthernetType(binary.BigEndian.Uint16(b[2:])), b[end:]
+}
+
+// parseGRE reads a version 0 GRE header (RFC 2784, RFC 2890).
+func parseGRE(b []byte) (*models.TunnelInfo, layers.EthernetType, []byte) {
+	if len(b) < 4 || b[1]&0x07 != 0 {
+		return nil, 0, nil
+	}
+	t := &models.TunnelInfo{Type: models.TunnelGRE}
+	flags := b[0]
+	off := 4
+	if flags&0x80 != 0 { // Checksum present
+		off += 4
+	}
+	if flags&0x20 != 0 { // Key present
+		if len(b) < off+4 {
+			return nil, 0, nil
+		}
+		t.VNI = binary.BigEndian.Uint32(b[off:])
+		off += 4
+	}
+	if flags&0x10 != 0 { // Sequence number present
+		off += 4
+	}
+	if len(b) < off {
+		return nil, 0, nil
+	}
+	return t, layers.EthernetType(binary.BigEndian.Uint16(b[2:])), b[off:]
+}
+
+func uint24(b []byte) uint32 {
+	return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+}
+
+// decodeTunnel decodes the packet carried by a tunnel with a nested decoder
+// and moves its details onto p. Tunnels inside tunnels are not followed.
+func (d *decoder) decodeTunnel(p *models.PacketData, ci gopacket.CaptureInfo) {
+	if d.nested {
+		return
+	}
+	t, etherType, data := d.tunnelPayload(p)
+	if t == nil {
+		return
+	}
+	p.Tunnel = t
+
+	var inner *decoder
+	switch etherType {
+	case layers.EthernetTypeTransparentEthernetBridging:
+		if d.innerEth == nil {
+			d.innerEth = newDecoder(layers.LinkTypeEthernet)
+			d.innerEth.nested = true
+		}
+		inner = d.innerEth
+	case layers.EthernetTypeIPv4, layers.EthernetTypeIPv6:
+		if d.innerIP == nil {
+			d.innerIP = newDecoder(layers.LinkTypeRaw)
+			d.innerIP.nested = true
+		}
+		inner = d.innerIP
+	default:
+		return
+	}
+
+	ip, ok := inner.decode(data, ci)
+	if !ok {
+		return
+	}
+	t.Family, t.SrcIP, t.DstIP = ip.Family, ip.SrcIP, ip.DstIP
+	t.SrcPort, t.DstPort, t.Protocol = ip.SrcPort, ip.DstPort, ip.Protocol
+
+	p.Protocols = append(p.Protocols, strings.ToLower(t.Type))
+	p.Protocols = append(p.Protocols, ip.Protocols...)
+	p.TCP, p.ICMP, p.DNS, p.TLS, p.HTTP = ip.TCP, ip.ICMP, ip.DNS, ip.TLS, ip.HTTP
+	p.QUIC = ip.QUIC
+	p.Names = ip.Names
+}
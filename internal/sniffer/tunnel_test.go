@@ -0,0 +1,178 @@
+package sniffer
+
+import (
+	"bytes"
+	"gonetwatch/internal/models"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func TestParseVXLAN(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []byte
+		wantVNI uint32
+		wantOK  bool
+	}{
+		{"vni", []byte{0x08, 0, 0, 0, 0x00, 0x13, 0x89, 0, 0xaa}, 5001, true},
+		{"vni flag unset", []byte{0x00, 0, 0, 0, 0x00, 0x13, 0x89, 0, 0xaa}, 0, false},
+		{"truncated", []byte{0x08, 0, 0, 0, 0x00, 0x13}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, etherType, rest := parseVXLAN(tt.in)
+			if (info != nil) != tt.wantOK {
+				t.Fatalf("parseVXLAN() = %v, want ok %v", info, tt.wantOK)
+			}
+			if !tt.wantOK {
+				return
+			}
+			if info.Type != models.TunnelVXLAN || info.VNI != tt.wantVNI {
+				t.Errorf("parseVXLAN() = %s %d, want VXLAN %d", info.Type, info.VNI, tt.wantVNI)
+			}
+			if etherType != layers.EthernetTypeTransparentEthernetBridging || !bytes.Equal(rest, []byte{0xaa}) {
+				t.Errorf("parseVXLAN() carries %v %x, want Ethernet aa", etherType, rest)
+			}
+		})
+	}
+}
+
+func TestParseGENEVE(t *testing.T) {
+	tests := []struct {
+		name          string
+		in            []byte
+		wantVNI       uint32
+		wantEtherType layers.EthernetType
+		wantRest      []byte
+		wantOK        bool
+	}{
+		{"no options", []byte{0x00, 0, 0x65, 0x58, 0, 0, 0x2a, 0, 0xaa}, 42, layers.EthernetTypeTransparentEthernetBridging, []byte{0xaa}, true},
+		{"options skipped", []byte{0x01, 0, 0x08, 0x00, 0, 0, 0x2a, 0, 1, 2, 3, 4, 0xaa}, 42, layers.EthernetTypeIPv4, []byte{0xaa}, true},
+		{"options truncated", []byte{0x02, 0, 0x08, 0x00, 0, 0, 0x2a, 0, 1, 2, 3, 4}, 0, 0, nil, false},
+		{"unknown version", []byte{0x40, 0, 0x65, 0x58, 0, 0, 0x2a, 0}, 0, 0, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, etherType, rest := parseGENEVE(tt.in)
+			if (info != nil) != tt.wantOK {
+				t.Fatalf("parseGENEVE() = %v, want ok %v", info, tt.wantOK)
+			}
+			if !tt.wantOK {
+				return
+			}
+			if info.Type != models.TunnelGENEVE || info.VNI != tt.wantVNI {
+				t.Errorf("parseGENEVE() = %s %d, want GENEVE %d", info.Type, info.VNI, tt.wantVNI)
+			}
+			if etherType != tt.wantEtherType || !bytes.Equal(rest, tt.wantRest) {
+				t.Errorf("parseGENEVE() carries %v %x, want %v %x", etherType, rest, tt.wantEtherType, tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestParseGRE(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       []byte
+		wantKey  uint32
+		wantRest []byte
+		wantOK   bool
+	}{
+		{"plain", []byte{0x00, 0, 0x08, 0x00, 0xaa}, 0, []byte{0xaa}, true},
+		{"key", []byte{0x20, 0, 0x08, 0x00, 0, 0, 0x01, 0x00, 0xaa}, 256, []byte{0xaa}, true},
+		{"checksum, key and sequence", []byte{0xb0, 0, 0x08, 0x00, 9, 9, 0, 0, 0, 0, 0, 7, 1, 1, 1, 1, 0xaa}, 7, []byte{0xaa}, true},
+		{"key truncated", []byte{0x20, 0, 0x08, 0x00, 0, 0}, 0, nil, false},
+		{"version 1", []byte{0x00, 0x01, 0x88, 0x0b}, 0, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, etherType, rest := parseGRE(tt.in)
+			if (info != nil) != tt.wantOK {
+				t.Fatalf("parseGRE() = %v, want ok %v", info, tt.wantOK)
+			}
+			if !tt.wantOK {
+				return
+			}
+			if info.Type != models.TunnelGRE || info.VNI != tt.wantKey {
+				t.Errorf("parseGRE() = %s %d, want GRE %d", info.Type, info.VNI, tt.wantKey)
+			}
+			if etherType != layers.EthernetTypeIPv4 || !bytes.Equal(rest, tt.wantRest) {
+				t.Errorf("parseGRE() carries %v %x, want IPv4 %x", etherType, rest, tt.wantRest)
+			}
+		})
+	}
+}
+
+// The outer decoder reuses one IPv4 layer for every frame, so an inner IPv4
+// header must never replace the outer addresses of the packet.
+func TestDecodeKeepsOuterAddresses(t *testing.T) {
+	outer := func(proto layers.IPProtocol) *layers.IPv4 {
+		return &layers.IPv4{Version: 4, TTL: 64, Protocol: proto,
+			SrcIP: net.IP{192, 0, 2, 1}, DstIP: net.IP{192, 0, 2, 2}}
+	}
+	inner := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolUDP,
+		SrcIP: net.IP{10, 0, 0, 1}, DstIP: net.IP{10, 0, 0, 2}}
+	innerUDP := &layers.UDP{SrcPort: 5000, DstPort: 5001}
+	innerUDP.SetNetworkLayerForChecksum(inner)
+	vxlanOuter := outer(layers.IPProtocolUDP)
+	vxlanUDP := &layers.UDP{SrcPort: 40000, DstPort: vxlanPort}
+	vxlanUDP.SetNetworkLayerForChecksum(vxlanOuter)
+
+	tests := []struct {
+		name       string
+		layers     []gopacket.SerializableLayer
+		wantTunnel string
+	}{
+		{
+			name: "GRE",
+			layers: []gopacket.SerializableLayer{outer(layers.IPProtocolGRE),
+				gopacket.Payload{0x00, 0, 0x08, 0x00}, inner, innerUDP},
+			wantTunnel: models.TunnelGRE,
+		},
+		{
+			name: "VXLAN",
+			layers: []gopacket.SerializableLayer{vxlanOuter, vxlanUDP,
+				gopacket.Payload{0x08, 0, 0, 0, 0, 0x13, 0x89, 0},
+				&layers.Ethernet{SrcMAC: net.HardwareAddr{2, 0, 0, 0, 0, 3}, DstMAC: net.HardwareAddr{2, 0, 0, 0, 0, 4}, EthernetType: layers.EthernetTypeIPv4},
+				inner, innerUDP},
+			wantTunnel: models.TunnelVXLAN,
+		},
+		{
+			name:   "IP in IP",
+			layers: []gopacket.SerializableLayer{outer(layers.IPProtocolIPv4), inner, innerUDP},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eth := &layers.Ethernet{SrcMAC: net.HardwareAddr{2, 0, 0, 0, 0, 1}, DstMAC: net.HardwareAddr{2, 0, 0, 0, 0, 2}, EthernetType: layers.EthernetTypeIPv4}
+			buf := gopacket.NewSerializeBuffer()
+			opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+			if err := gopacket.SerializeLayers(buf, opts, append([]gopacket.SerializableLayer{eth}, tt.layers...)...); err != nil {
+				t.Fatal(err)
+			}
+			data := buf.Bytes()
+
+			d := newDecoder(layers.LinkTypeEthernet)
+			p, ok := d.decode(data, gopacket.CaptureInfo{Timestamp: time.Unix(0, 0), CaptureLength: len(data), Length: len(data)})
+			if !ok {
+				t.Fatal("decode() failed")
+			}
+			if p.SrcIP != "192.0.2.1" || p.DstIP != "192.0.2.2" {
+				t.Errorf("decode() addresses = %s -> %s, want the outer 192.0.2.1 -> 192.0.2.2", p.SrcIP, p.DstIP)
+			}
+			if tt.wantTunnel == "" {
+				return
+			}
+			if p.Tunnel == nil {
+				t.Fatal("decode() found no tunnel")
+			}
+			if p.Tunnel.Type != tt.wantTunnel || p.Tunnel.SrcIP != "10.0.0.1" || p.Tunnel.DstIP != "10.0.0.2" || p.Tunnel.DstPort != 5001 {
+				t.Errorf("decode() tunnel = %+v, want %s carrying 10.0.0.1 -> 10.0.0.2:5001", *p.Tunnel, tt.wantTunnel)
+			}
+		})
+	}
+}
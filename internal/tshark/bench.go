@@ -0,0 +1,81 @@
+package tshark
+
+import (
+	"bytes"
+	"fmt"
+	"gonetwatch/internal/capture"
+	"gonetwatch/internal/models"
+	"os/exec"
+	"time"
+)
+
+// benchRounds is how many times each recorded output is decoded.
+const benchRounds = 5
+
+// BenchResult is the decoding throughput measured for one output format.
+type BenchResult struct {
+	Format      string
+	Packets     int64         // Packets decoded per round
+	OutputBytes int           // Size of the recorded tshark output
+	Rounds      int           // Times the output was decoded
+	Elapsed     time.Duration // Decoding time over all rounds
+}
+
+// PacketsPerSecond returns the decoding rate.
+func (r BenchResult) PacketsPerSecond() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Packets) * float64(r.Rounds) / r.Elapsed.Seconds()
+}
+
+// Benchmark measures how fast each output format is decoded. tshark's output
+// for the capture file is recorded once per format and then decoded from
+// memory, so only our side of the pipeline is timed, not tshark's dissection.
+func Benchmark(path string, extra []models.FieldSpec) ([]BenchResult, error) {
+	var results []BenchResult
+	for _, format := range Formats() {
+		src, err := NewSource(capture.Config{File: path, ExtraFields: extra, OutputFormat: format})
+		if err != nil {
+			return nil, err
+		}
+		s := src.(*Source)
+
+		out, err := exec.Command("tshark", s.commandArgs()...).Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to record tshark %s output: %v", format, err)
+		}
+		results = append(results, s.benchDecode(out))
+	}
+	return results, nil
+}
+
+// benchDecode parses recorded output line by line, as decode does, without
+// delivering the packets.
+func (s *Source) benchDecode(out []byte) BenchResult {
+	r := BenchResult{Format: s.format, OutputBytes: len(out), Rounds: benchRounds}
+
+	start := time.Now()
+	for range benchRounds {
+		r.Packets = s.decodeOutput(out)
+	}
+	r.Elapsed = time.Since(start)
+	return r
+}
+
+// decodeOutput parses recorded output and returns the number of packets it held.
+func (s *Source) decodeOutput(out []byte) int64 {
+	var packets int64
+	for rest := out; len(rest) > 0; {
+		line := rest
+		if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+			line, rest = rest[:i+1], rest[i+1:]
+		} else {
+			rest = nil
+		}
+		if len(bytes.TrimSpace(line)) > 0 && s.parseLine(line) != nil {
+			packets++
+		}
+	}
+	return packets
+}
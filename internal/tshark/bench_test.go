@@ -0,0 +1,53 @@
+package tshark
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// The samples in testdata are synthetic, not captured: they follow the shape
+// of "tshark -T ek -e ..." and "tshark -T fields -E header=y -e ..." output,
+// with the separators of formatArgs, for the same mix of DNS, TCP, TLS, HTTP,
+// ICMP, QUIC, mDNS and DHCP packets in both files. They measure the cost of
+// decoding, not how any tshark version lays out its output. The header line
+// of the fields sample names its columns, so the sample stays usable when
+// fields are added.
+
+func BenchmarkDecodeEK(b *testing.B) {
+	benchmarkDecode(b, FormatEK, "testdata/sample.ek")
+}
+
+func BenchmarkDecodeFields(b *testing.B) {
+	benchmarkDecode(b, FormatFields, "testdata/sample.fields")
+}
+
+// benchmarkDecode decodes a recorded output repeatedly and reports the
+// decoding rate in packets per second.
+func benchmarkDecode(b *testing.B, format, path string) {
+	out, err := os.ReadFile(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	s := newSource()
+	s.format = format
+	if format == FormatFields {
+		header, rest, _ := bytes.Cut(out, []byte("\n"))
+		s.columns = strings.Split(string(header), columnSeparator)
+		out = rest
+	}
+
+	packets := s.decodeOutput(out)
+	if packets == 0 || s.malformed.Load() > 0 {
+		b.Fatalf("%s: %d packets decoded, %d malformed lines", path, packets, s.malformed.Load())
+	}
+
+	b.SetBytes(int64(len(out)))
+	var total int64
+	for b.Loop() {
+		total += s.decodeOutput(out)
+	}
+	b.ReportMetric(float64(total)/b.Elapsed().Seconds(), "packets/s")
+}
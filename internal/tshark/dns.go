@@ -0,0 +1,56 @@
+package tshark
+
+import (
+	"gonetwatch/internal/models"
+	"strconv"
+)
+
+// dnsPort is the port DNS messages are counted on. tshark fills the same
+// fields for mDNS and LLMNR, which are name announcements rather than lookups
+// (see convertNames), and the native backend only decodes DNS on this port.
+const dnsPort = 53
+
+// convertDNS extracts the DNS message of a packet, or returns nil if it has none.
+func convertDNS(l EkLayers, srcPort, dstPort int) *models.DNSInfo {
+	if len(l.DNSID) == 0 && len(l.DNSQryName) == 0 {
+		return nil
+	}
+	if srcPort != dnsPort && dstPort != dnsPort {
+		return nil
+	}
+
+	d := &models.DNSInfo{}
+	if len(l.DNSID) > 0 {
+		// Rendered in hex, e.g. "0x1a2b"
+		if id, err := strconv.ParseUint(l.DNSID[0], 0, 16); err == nil {
+			d.ID = uint16(id)
+		}
+	}
+	if len(l.DNSResponse) > 0 {
+		d.Response = parseFlag(l.DNSResponse[0])
+	}
+	if len(l.DNSRCode) > 0 {
+		d.RCode, _ = strconv.Atoi(l.DNSRCode[0])
+	}
+	if len(l.DNSQryName) > 0 {
+		d.QueryName = l.DNSQryName[0]
+	}
+	if len(l.DNSQryType) > 0 {
+		if t, err := strconv.ParseUint(l.DNSQryType[0], 0, 16); err == nil {
+			d.QueryType = uint16(t)
+		}
+	}
+	for _, answers := range [][]string{l.DNSA, l.DNSAAAA, l.DNSCNAME} {
+		for _, a := range answers {
+			d.Answers = append(d.Answers, models.CanonicalIP(a))
+		}
+	}
+	return d
+}
+
+// parseFlag reads a boolean field, which tshark renders as "1"/"0" or
+// "true"/"false" depending on version and output format.
+func parseFlag(s string) bool {
+	b, err := strconv.ParseBool(s)
+	return err == nil && b
+}
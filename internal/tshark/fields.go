@@ -0,0 +1,133 @@
+package tshark
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gonetwatch/internal/models"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// ResolveFields checks that tshark knows every requested field and fills in
+// the kind of fields requested without an explicit type.
+func ResolveFields(specs []models.FieldSpec) ([]models.FieldSpec, error) {
+	types, err := fieldTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make([]models.FieldSpec, 0, len(specs))
+	for _, spec := range specs {
+		ftype, ok := types[spec.Name]
+		if !ok {
+			return nil, fmt.Errorf("tshark does not know the field %q", spec.Name)
+		}
+		if spec.Kind == models.FieldAuto {
+			spec.Kind = kindForType(ftype)
+		}
+		resolved = append(resolved, spec)
+	}
+	return resolved, nil
+}
+
+// optionalFields are decoded when the installed tshark knows them.
+// They were added in later releases, and tshark refuses to start when
+// asked for a field it does not know.
+var optionalFields = []string{
+	"tls.handshake.extensions.supported_version",
+	"tls.handshake.ja3_hash",
+	"tls.handshake.ja4",
+	"dhcp.client_id",
+	"nbns.nb_flags.group",
+}
+
+var (
+	optionalOnce      sync.Once
+	optionalAvailable []string
+)
+
+// availableOptionalFields returns the optional fields the installed tshark
+// knows. tshark is only asked once.
+func availableOptionalFields() []string {
+	optionalOnce.Do(func() {
+		types, err := fieldTypes()
+		if err != nil {
+			return
+		}
+		for _, name := range optionalFields {
+			if _, ok := types[name]; ok {
+				optionalAvailable = append(optionalAvailable, name)
+			}
+		}
+	})
+	return optionalAvailable
+}
+
+// fieldTypes returns the FT_* type of every field and protocol tshark can dissect.
+func fieldTypes() (map[string]string, error) {
+	out, err := exec.Command("tshark", "-G", "fields").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tshark fields: %v", err)
+	}
+
+	types := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		// F <name> <abbrev> <type> ... for fields, P <name> <abbrev> for protocols
+		cols := strings.Split(scanner.Text(), "\t")
+		switch {
+		case len(cols) >= 4 && cols[0] == "F":
+			types[cols[2]] = cols[3]
+		case len(cols) >= 3 && cols[0] == "P":
+			types[cols[2]] = "FT_PROTOCOL"
+		}
+	}
+	return types, scanner.Err()
+}
+
+// kindForType maps a tshark field type onto the kind it is decoded as.
+func kindForType(ftype string) models.FieldKind {
+	switch {
+	case strings.HasPrefix(ftype, "FT_UINT"), strings.HasPrefix(ftype, "FT_INT"), ftype == "FT_FRAMENUM":
+		return models.FieldInt
+	case ftype == "FT_FLOAT", ftype == "FT_DOUBLE", ftype == "FT_RELATIVE_TIME":
+		return models.FieldFloat
+	case ftype == "FT_BOOLEAN":
+		return models.FieldBool
+	default:
+		return models.FieldString
+	}
+}
+
+// extractExtra decodes the user-requested fields from the layers object of an
+// EK packet line. Values that do not parse as their declared kind are dropped.
+func (s *Source) extractExtra(layers json.RawMessage) map[string][]models.FieldValue {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(layers, &fields); err != nil {
+		return nil
+	}
+
+	extra := make(map[string][]models.FieldValue, len(s.extra))
+	for _, spec := range s.extra {
+		// In EK output the dots of field names are replaced with underscores
+		var rawValues []string
+		if err := json.Unmarshal(fields[strings.ReplaceAll(spec.Name, ".", "_")], &rawValues); err != nil {
+			continue
+		}
+		appendFieldValues(extra, spec, rawValues)
+	}
+	return extra
+}
+
+// appendFieldValues parses the raw values of an extra field onto extra.
+// Values that do not parse as the field's kind are dropped.
+func appendFieldValues(extra map[string][]models.FieldValue, spec models.FieldSpec, rawValues []string) {
+	for _, rawValue := range rawValues {
+		if v, err := models.ParseFieldValue(spec.Kind, rawValue); err == nil {
+			extra[spec.Name] = append(extra[spec.Name], v)
+		}
+	}
+}
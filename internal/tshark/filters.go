@@ -0,0 +1,143 @@
+package tshark
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// emptyPcap is a pcap file header without packets (Ethernet link type).
+// Display filters are validated by letting tshark read it.
+var emptyPcap = []byte{
+	0xd4, 0xc3, 0xb2, 0xa1, // magic
+	0x02, 0x00, 0x04, 0x00, // version 2.4
+	0x00, 0x00, 0x00, 0x00, // thiszone
+	0x00, 0x00, 0x00, 0x00, // sigfigs
+	0xff, 0xff, 0x00, 0x00, // snaplen
+	0x01, 0x00, 0x00, 0x00, // Ethernet
+}
+
+// Validate checks the capture and display filters before the capture starts,
+// so syntax errors are reported clearly instead of as a tshark exit.
+func (s *Source) Validate() error {
+	s.mu.Lock()
+	captureFilter, displayFilter := s.captureFilter, s.displayFilter
+	s.mu.Unlock()
+
+	return s.validateFilters(captureFilter, displayFilter)
+}
+
+func (s *Source) validateFilters(captureFilter, displayFilter string) error {
+	if displayFilter != "" {
+		if err := ValidateDisplayFilter(displayFilter); err != nil {
+			return err
+		}
+	}
+	if captureFilter != "" && s.live {
+		for _, iface := range s.interfaces {
+			if err := ValidateCaptureFilter(iface, captureFilter); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SetFilters replaces the filters of a live capture. tshark is restarted
+// with the new filters once they have been validated.
+func (s *Source) SetFilters(captureFilter, displayFilter string) error {
+	if !s.live {
+		return errors.New("filters can only be changed during a live capture")
+	}
+	if err := s.validateFilters(captureFilter, displayFilter); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.captureFilter, s.displayFilter = captureFilter, displayFilter
+	cancel := s.runCancel
+	if cancel != nil {
+		s.restartNow = true
+	}
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	return nil
+}
+
+// ValidateDisplayFilter checks a Wireshark display filter for syntax errors.
+func ValidateDisplayFilter(filter string) error {
+	f, err := os.CreateTemp("", "gonetwatch-*.pcap")
+	if err != nil {
+		return fmt.Errorf("failed to validate display filter: %v", err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(emptyPcap)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("failed to validate display filter: %v", err)
+	}
+
+	out, err := exec.Command("tshark", "-n", "-r", f.Name(), "-Y", filter).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("invalid display filter %q: %s", filter, toolMessage(out, err))
+	}
+	return nil
+}
+
+// ValidateCaptureFilter checks a BPF capture filter by compiling it for the
+// interface with dumpcap, which tshark also relies on for live captures.
+func ValidateCaptureFilter(interfaceName, filter string) error {
+	dumpcap, err := dumpcapPath()
+	if err != nil {
+		return fmt.Errorf("cannot validate capture filter %q: %v", filter, err)
+	}
+
+	args := []string{"-d", "-f", filter}
+	if interfaceName != "" {
+		args = append([]string{"-i", interfaceName}, args...)
+	}
+	out, err := exec.Command(dumpcap, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("invalid capture filter %q: %s", filter, toolMessage(out, err))
+	}
+	return nil
+}
+
+// dumpcapPath finds dumpcap on the PATH or, as some packages install it, next
+// to tshark.
+func dumpcapPath() (string, error) {
+	if path, err := exec.LookPath("dumpcap"); err == nil {
+		return path, nil
+	}
+	if tshark, err := exec.LookPath("tshark"); err == nil {
+		path := filepath.Join(filepath.Dir(tshark), "dumpcap")
+		if _, err := exec.LookPath(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", errors.New("dumpcap not found; install it with tshark for live captures")
+}
+
+// toolMessage extracts the diagnostic from tshark/dumpcap output.
+func toolMessage(out []byte, err error) string {
+	// Drop status lines and program name prefixes, and join multi-line messages
+	var lines []string
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || isStatusLine(line) {
+			continue
+		}
+		line = strings.TrimPrefix(line, "tshark: ")
+		lines = append(lines, strings.TrimPrefix(line, "dumpcap: "))
+	}
+	if len(lines) == 0 {
+		return err.Error()
+	}
+	return strings.Join(lines, " ")
+}
@@ -0,0 +1,92 @@
+package tshark
+
+import (
+	"gonetwatch/internal/models"
+	"slices"
+	"strings"
+)
+
+// Output formats tshark can be run with.
+const (
+	// FormatEK is Elasticsearch JSON (-T ek), one object per packet. It is the default.
+	FormatEK = "ek"
+	// FormatFields is delimited text (-T fields), one column per field.
+	// It carries the same fields as EK but is much cheaper to decode.
+	FormatFields = "fields"
+)
+
+// Separators for the fields format. Control characters are used so that
+// string values, e.g. of extra fields, cannot be confused with them.
+const (
+	columnSeparator = "\x1f" // Between the columns of a line
+	valueSeparator  = "\x1e" // Between the occurrences of a field within a column
+)
+
+// Formats returns the supported output formats.
+func Formats() []string {
+	return []string{FormatEK, FormatFields}
+}
+
+// formatArgs returns the tshark options selecting the output format.
+func formatArgs(format string) []string {
+	if format == FormatFields {
+		return []string{
+			"-T", "fields",
+			"-E", "separator=" + columnSeparator,
+			"-E", "aggregator=" + valueSeparator,
+			"-E", "occurrence=a",
+			"-E", "quote=n",
+		}
+	}
+	return []string{"-T", "ek"}
+}
+
+// columnList returns the fields tshark is asked for with -e: the base
+// fields, then the optional ones, then any extra fields not already among them.
+func columnList(optional []string, extra []models.FieldSpec) []string {
+	columns := slices.Concat(baseFields, optional)
+	for _, spec := range extra {
+		if !slices.Contains(columns, spec.Name) {
+			columns = append(columns, spec.Name)
+		}
+	}
+	return columns
+}
+
+// parseFieldsLine decodes one line of -T fields output, whose columns follow
+// s.columns. Lines with the wrong number of columns are counted as malformed.
+func (s *Source) parseFieldsLine(line []byte) *models.PacketData {
+	// A single conversion; the column values below are substrings of it
+	text := strings.TrimRight(string(line), "\r\n")
+	if strings.Count(text, columnSeparator) != len(s.columns)-1 {
+		s.malformed.Add(1)
+		return nil
+	}
+
+	var ek EkPacket
+	var extra map[string][]models.FieldValue
+	if len(s.extra) > 0 {
+		extra = make(map[string][]models.FieldValue, len(s.extra))
+	}
+	for _, name := range s.columns {
+		var value string
+		value, text, _ = strings.Cut(text, columnSeparator)
+		if value == "" {
+			continue
+		}
+
+		values := strings.Split(value, valueSeparator)
+		if slot := ek.Layers.slot(name); slot != nil {
+			*slot = values
+		}
+		for _, spec := range s.extra {
+			if spec.Name == name {
+				appendFieldValues(extra, spec, values)
+			}
+		}
+	}
+
+	pkt := convertToModel(ek)
+	pkt.Extra = extra
+	return pkt
+}
@@ -0,0 +1,77 @@
+package tshark
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseFieldsLine(t *testing.T) {
+	columns := []string{"frame.len", "frame.time_epoch", "frame.protocols", "vlan.id", "ip.src", "ip.dst", "tcp.srcport", "tcp.dstport"}
+	line := func(values ...string) []byte {
+		return []byte(strings.Join(values, columnSeparator) + "\n")
+	}
+
+	tests := []struct {
+		name          string
+		line          []byte
+		wantOK        bool
+		wantLength    int
+		wantSrc       string
+		wantDstPort   int
+		wantVLANs     []uint16
+		wantMalformed int64
+	}{
+		{
+			name:        "tcp",
+			line:        line("74", "1700000000.5", "eth:ethertype:ip:tcp", "", "10.0.0.1", "10.0.0.2", "40000", "443"),
+			wantOK:      true,
+			wantLength:  74,
+			wantSrc:     "10.0.0.1",
+			wantDstPort: 443,
+		},
+		{
+			name:       "stacked vlans",
+			line:       line("60", "1700000000.5", "eth:ethertype:vlan:ethertype:vlan:ethertype:ip", "100"+valueSeparator+"200", "10.0.0.1", "10.0.0.2", "", ""),
+			wantOK:     true,
+			wantLength: 60,
+			wantSrc:    "10.0.0.1",
+			wantVLANs:  []uint16{100, 200},
+		},
+		{
+			name:          "missing column",
+			line:          line("74", "1700000000.5", "eth:ethertype:ip:tcp", "", "10.0.0.1", "10.0.0.2", "40000"),
+			wantMalformed: 1,
+		},
+		{
+			name:          "extra column",
+			line:          line("74", "1700000000.5", "eth:ethertype:ip:tcp", "", "10.0.0.1", "10.0.0.2", "40000", "443", "x"),
+			wantMalformed: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSource()
+			s.format = FormatFields
+			s.columns = columns
+
+			pkt := s.parseFieldsLine(tt.line)
+			if (pkt != nil) != tt.wantOK {
+				t.Fatalf("parseFieldsLine() = %v, want ok %v", pkt, tt.wantOK)
+			}
+			if got := s.malformed.Load(); got != tt.wantMalformed {
+				t.Errorf("malformed = %d, want %d", got, tt.wantMalformed)
+			}
+			if pkt == nil {
+				return
+			}
+			if pkt.Length != tt.wantLength || pkt.SrcIP != tt.wantSrc || pkt.DstPort != tt.wantDstPort {
+				t.Errorf("parseFieldsLine() = length %d, src %s, dst port %d, want %d, %s, %d",
+					pkt.Length, pkt.SrcIP, pkt.DstPort, tt.wantLength, tt.wantSrc, tt.wantDstPort)
+			}
+			if !slices.Equal(pkt.VLANs, tt.wantVLANs) {
+				t.Errorf("parseFieldsLine() VLANs = %v, want %v", pkt.VLANs, tt.wantVLANs)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package tshark
+
+import (
+	"gonetwatch/internal/models"
+	"strconv"
+	"strings"
+)
+
+// convertHTTP extracts the HTTP request or response started by a packet,
+// or returns nil if it has none.
+func convertHTTP(l EkLayers) *models.HTTPInfo {
+	if len(l.HTTPMethod) > 0 {
+		h := &models.HTTPInfo{Request: true, Method: l.HTTPMethod[0]}
+		if len(l.HTTPHost) > 0 {
+			h.Host = l.HTTPHost[0]
+		}
+		if len(l.HTTPURI) > 0 {
+			h.Path, _, _ = strings.Cut(l.HTTPURI[0], "?")
+		}
+		if len(l.HTTPUserAgent) > 0 {
+			h.UserAgent = l.HTTPUserAgent[0]
+		}
+		return h
+	}
+	if len(l.HTTPStatus) > 0 {
+		if code, err := strconv.Atoi(l.HTTPStatus[0]); err == nil {
+			return &models.HTTPInfo{StatusCode: code}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package tshark
+
+import (
+	"gonetwatch/internal/models"
+	"strconv"
+)
+
+// convertICMP extracts the ICMP or ICMPv6 header of a packet, or returns nil
+// if it has none. Only the first occurrence of each field is used; further
+// ones belong to a packet quoted by an error message.
+func convertICMP(l EkLayers) *models.ICMPInfo {
+	typ, code, id, seq, mtu := l.ICMPType, l.ICMPCode, l.ICMPIdent, l.ICMPSeq, l.ICMPMTU
+	v6 := len(typ) == 0
+	if v6 {
+		typ, code, id, seq, mtu = l.ICMPv6Type, l.ICMPv6Code, l.ICMPv6Ident, l.ICMPv6Seq, l.ICMPv6MTU
+	}
+	if len(typ) == 0 {
+		return nil
+	}
+
+	i := &models.ICMPInfo{V6: v6}
+	if v, err := strconv.ParseUint(typ[0], 0, 8); err == nil {
+		i.Type = uint8(v)
+	}
+	if len(code) > 0 {
+		if v, err := strconv.ParseUint(code[0], 0, 8); err == nil {
+			i.Code = uint8(v)
+		}
+	}
+	if len(id) > 0 {
+		if v, err := strconv.ParseUint(id[0], 0, 16); err == nil {
+			i.ID = uint16(v)
+		}
+	}
+	if len(seq) > 0 {
+		if v, err := strconv.ParseUint(seq[0], 0, 16); err == nil {
+			i.Seq = uint16(v)
+		}
+	}
+	if len(mtu) > 0 {
+		i.MTU, _ = strconv.Atoi(mtu[0])
+	}
+	return i
+}
@@ -0,0 +1,41 @@
+package tshark
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Interface is a capture interface as listed by tshark -D.
+type Interface struct {
+	Name        string
+	Description string // e.g. "Loopback", may be empty
+}
+
+// ListInterfaces returns the interfaces tshark can capture from, in tshark's order.
+func ListInterfaces() ([]Interface, error) {
+	out, err := exec.Command("tshark", "-D").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tshark interfaces: %v", err)
+	}
+
+	var list []Interface
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		// "3. lo (Loopback)"
+		_, entry, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ". ")
+		if !ok {
+			continue
+		}
+		var iface Interface
+		if i := strings.LastIndex(entry, " ("); i >= 0 && strings.HasSuffix(entry, ")") {
+			iface.Name, iface.Description = entry[:i], entry[i+2:len(entry)-1]
+		} else {
+			iface.Name = entry
+		}
+		list = append(list, iface)
+	}
+	return list, scanner.Err()
+}
@@ -2,94 +2,437 @@ package tshark
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"gonetwatch/internal/capture"
 	"gonetwatch/internal/models"
+	"io"
 	"os"
 	"os/exec"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// StartCapture begins the tshark process and streams parsed packets to the out channel.
-func StartCapture(interfaceName string, captureFilter string, out chan<- models.PacketData) error {
+func init() {
+	capture.Register("tshark", NewSource)
+}
+
+// Source runs tshark and streams the packets it decodes.
+// It captures live from an interface or reads a capture file. Live captures
+// are supervised: if tshark dies it is restarted with exponential backoff.
+type Source struct {
+	interfaces []string
+	file       string
+	extra      []models.FieldSpec
+	format     string   // Output format, FormatEK or FormatFields
+	columns    []string // Fields requested with -e, in order
+	ring       capture.RingBuffer
+	sampler    *capture.Sampler // nil unless sampling
+	live       bool
+	cancel     context.CancelFunc
+	done       chan struct{}
+	out        chan models.PacketData
+	errs       chan error
+	packets    atomic.Int64
+	restarts   atomic.Int64
+	kernelDrop atomic.Int64
+	pipeDrop   atomic.Int64
+	malformed  atomic.Int64
+	skipped    atomic.Int64
+	sampledOut atomic.Int64
+
+	mu            sync.Mutex
+	captureFilter string
+	displayFilter string
+	runCancel     context.CancelFunc // Stops the current tshark process
+	restartNow    bool               // The current process was stopped to apply new filters
+
+	ifDropsMu   sync.Mutex
+	ifDropsBase map[string]uint64 // Interface drop counters when the capture started
+	ifDrops     int64             // Interface drops since then, as last read
+}
+
+// NewSource creates a tshark source from a capture configuration.
+func NewSource(cfg capture.Config) (capture.Source, error) {
+	var s *Source
+	if cfg.File != "" {
+		if cfg.CaptureFilter != "" {
+			return nil, errors.New("capture filters (-f) only apply to live captures; use a display filter (-Y) with -r")
+		}
+		var err error
+		if s, err = NewFileSource(cfg.File); err != nil {
+			return nil, err
+		}
+	} else {
+		s = NewLiveSource(cfg.Interfaces, cfg.CaptureFilter)
+	}
+	if cfg.Ring.Enabled() {
+		if cfg.File != "" {
+			return nil, errors.New("the ring buffer only applies to live captures")
+		}
+		s.ring = cfg.Ring
+	}
+	s.sampler = capture.NewSampler(cfg.Sampling)
+	s.displayFilter = cfg.DisplayFilter
+
+	switch cfg.OutputFormat {
+	case "", FormatEK:
+	case FormatFields:
+		s.format = FormatFields
+	default:
+		return nil, fmt.Errorf("unknown tshark output format %q (available: %v)", cfg.OutputFormat, Formats())
+	}
+
+	if len(cfg.ExtraFields) > 0 {
+		extra, err := ResolveFields(cfg.ExtraFields)
+		if err != nil {
+			return nil, err
+		}
+		s.extra = extra
+	}
+	s.columns = columnList(availableOptionalFields(), s.extra)
+	return s, nil
+}
+
+// NewLiveSource creates a source capturing from one or more network interfaces
+// with a single tshark process.
+func NewLiveSource(interfaces []string, captureFilter string) *Source {
+	s := newSource()
+	s.interfaces = interfaces
+	s.captureFilter = captureFilter
+	s.live = true
+	return s
+}
+
+// NewFileSource creates a source reading a .pcap/.pcapng file.
+// The packet channel is closed once the whole file has been read.
+func NewFileSource(path string) (*Source, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("cannot read capture file: %v", err)
+	}
+
+	s := newSource()
+	s.file = path
+	return s, nil
+}
+
+func newSource() *Source {
+	return &Source{
+		format:  FormatEK,
+		columns: baseFields,
+		done:    make(chan struct{}),
+		out:     make(chan models.PacketData, 1000),
+		errs:    make(chan error, 16),
+	}
+}
+
+// Start launches the tshark supervisor. tshark is stopped when ctx is
+// cancelled or Stop is called.
+func (s *Source) Start(ctx context.Context) error {
+	if _, err := exec.LookPath("tshark"); err != nil {
+		return fmt.Errorf("tshark not found: %v", err)
+	}
+
+	if s.live {
+		s.ifDropsBase = make(map[string]uint64)
+		for _, iface := range s.interfaces {
+			if n, ok := capture.InterfaceDrops(iface); ok {
+				s.ifDropsBase[iface] = n
+			}
+		}
+	}
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	go s.supervise(ctx)
+	return nil
+}
+
+// baseFields are the fields every packet is decoded from (see EkLayers and
+// EkLayers.slot).
+var baseFields = []string{
+	"frame.len", "frame.time_epoch", "frame.protocols", "frame.interface_name",
+	"eth.src", "eth.dst", "eth.type",
+	"vlan.etype", "vlan.id", "stp.protocol",
+	"vxlan.vni", "geneve.vni", "gre.key",
+	"ip.src", "ip.dst",
+	"ipv6.src", "ipv6.dst",
+	"tcp.srcport", "tcp.dstport",
+	"tcp.flags", "tcp.window_size_value",
+	"tcp.analysis.retransmission", "tcp.analysis.duplicate_ack", "tcp.analysis.zero_window",
+	"icmp.type", "icmp.code", "icmp.ident", "icmp.seq", "icmp.mtu",
+	"icmpv6.type", "icmpv6.code", "icmpv6.echo.identifier", "icmpv6.echo.sequence_number", "icmpv6.mtu",
+	"udp.srcport", "udp.dstport",
+	"dns.id", "dns.flags.response", "dns.flags.rcode", "dns.qry.name", "dns.qry.type",
+	"dns.a", "dns.aaaa", "dns.cname", "dns.resp.name", "dns.resp.type",
+	"dhcp.option.dhcp", "dhcp.option.hostname", "dhcp.hw.mac_addr", "dhcp.ip.client", "dhcp.ip.your",
+	"nbns.flags.response", "nbns.flags.opcode", "nbns.flags.rcode", "nbns.name", "nbns.addr",
+	"tls.handshake.type", "tls.handshake.extensions_server_name",
+	"tls.handshake.extensions_alpn_str", "tls.handshake.version",
+	"http.request.method", "http.host", "http.request.uri", "http.user_agent", "http.response.code",
+	"quic.header_form", "quic.long.packet_type", "quic.version", "quic.dcid", "quic.scid",
+}
+
+// commandArgs returns the full tshark command line.
+func (s *Source) commandArgs() []string {
 	// Construct the tshark command
 	// -l: flush stdout after each packet
 	// -n: disable name resolution
-	// -T ek: output in Elasticsearch JSON format
+	// -T ek or -T fields: output format (see formatArgs)
 	// -e ...: fields to extract
-	args := []string{
-		"-l", "-n", "-T", "ek",
-		"-e", "frame.len",
-		"-e", "ip.src", "-e", "ip.dst",
-		"-e", "tcp.srcport", "-e", "tcp.dstport",
-		"-e", "udp.srcport", "-e", "udp.dstport",
+	var args []string
+
+	s.mu.Lock()
+	// A capture filter given before the first -i applies to every interface
+	if s.captureFilter != "" {
+		args = append(args, "-f", s.captureFilter)
 	}
+	if s.file != "" {
+		args = append(args, "-r", s.file)
+	}
+	for _, iface := range s.interfaces {
+		args = append(args, "-i", iface)
+	}
+	if s.displayFilter != "" {
+		args = append(args, "-Y", s.displayFilter)
+	}
+	s.mu.Unlock()
 
-	if interfaceName != "" {
-		args = append([]string{"-i", interfaceName}, args...)
+	if s.ring.Enabled() {
+		args = append(args, ringArgs(s.ring)...)
 	}
 
-	if captureFilter != "" {
-		args = append(args, "-f", captureFilter)
+	args = append(args, "-l", "-n")
+	args = append(args, formatArgs(s.format)...)
+	for _, field := range s.columns {
+		args = append(args, "-e", field)
 	}
+	return args
+}
 
-	cmd := exec.Command("tshark", args...)
+// ringArgs returns the options making tshark also write the ring buffer.
+// -P keeps packets flowing to stdout while they are written to disk.
+func ringArgs(r capture.RingBuffer) []string {
+	args := []string{"-w", r.Path, "-P"}
+	if r.MaxSize > 0 {
+		// tshark counts file sizes in kB
+		args = append(args, "-b", fmt.Sprintf("filesize:%d", max(1, r.MaxSize/1000)))
+	}
+	if r.MaxFiles > 0 {
+		args = append(args, "-b", fmt.Sprintf("files:%d", r.MaxFiles))
+	}
+	if r.MaxDuration > 0 {
+		args = append(args, "-b", fmt.Sprintf("duration:%d", max(1, int64(r.MaxDuration/time.Second))))
+	}
+	return args
+}
 
-	cmd.Stderr = os.Stderr
+// decode reads tshark's output until EOF or until ctx is cancelled.
+// Lines are read whole whatever their length, and a line that cannot be
+// decoded is counted and skipped without ending the capture.
+func (s *Source) decode(ctx context.Context, stdout io.Reader) {
+	reader := bufio.NewReader(stdout)
 
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("failed to get stdout pipe: %v", err)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if !s.decodeLine(ctx, line) {
+				return
+			}
+		}
+		if err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				s.reportError(fmt.Errorf("reading tshark output: %v", err))
+			}
+			return
+		}
 	}
+}
 
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start tshark: %v", err)
+// decodeLine handles one line of tshark output and reports false once ctx is done.
+// Sampled-out packets are dropped here, before they cost any decoding.
+func (s *Source) decodeLine(ctx context.Context, line []byte) bool {
+	if s.sampler != nil && s.isPacketLine(line) && !s.sampler.Keep() {
+		s.sampledOut.Add(1)
+		return true
 	}
 
-	go func() {
-		scanner := bufio.NewScanner(stdout)
-		defer cmd.Wait() // simple cleanup, though we might need better process management later
+	pkt := s.parseLine(line)
+	if pkt == nil {
+		return true
+	}
+	if pkt.Interface == "" && len(s.interfaces) == 1 {
+		pkt.Interface = s.interfaces[0]
+	}
+	return s.deliver(ctx, *pkt)
+}
 
-		for scanner.Scan() {
-			line := scanner.Text()
-			
-			if strings.TrimSpace(line) == "" {
-				continue
-			}
+// isPacketLine reports whether a line carries a packet, judging only by its
+// start. In EK output every other line is a bulk index line.
+func (s *Source) isPacketLine(line []byte) bool {
+	if s.format == FormatFields {
+		return true
+	}
+	return !bytes.HasPrefix(bytes.TrimSpace(line), []byte(`{"index"`))
+}
 
-			// Tshark -T ek outputs an index line before each packet sometimes, or just packet lines.
-			// We look for lines containing "layers".
-			if !strings.Contains(line, "\"layers\"") {
-				continue
-			}
+// parseLine decodes one line in the source's output format. It returns nil
+// for lines that carry no packet, counting them as skipped or malformed.
+func (s *Source) parseLine(line []byte) *models.PacketData {
+	if s.format == FormatFields {
+		return s.parseFieldsLine(line)
+	}
+	return s.parseEKLine(line)
+}
 
-			var ekPkt EkPacket
-			if err := json.Unmarshal([]byte(line), &ekPkt); err != nil {
-				// Skip malformed lines
-				continue
-			}
+// parseEKLine decodes one line of EK output. tshark writes a bulk index line
+// before each packet line; the two are told apart by their top-level keys.
+func (s *Source) parseEKLine(line []byte) *models.PacketData {
+	var ek ekLine
+	if err := json.Unmarshal(line, &ek); err != nil {
+		s.malformed.Add(1)
+		return nil
+	}
+	if ek.Layers == nil {
+		if ek.Index == nil {
+			s.skipped.Add(1)
+		}
+		return nil
+	}
 
-			pkt := convertToModel(ekPkt)
-			if pkt != nil {
-				out <- *pkt
-			}
+	ekPkt := EkPacket{Timestamp: ek.Timestamp}
+	if err := json.Unmarshal(ek.Layers, &ekPkt.Layers); err != nil {
+		s.malformed.Add(1)
+		return nil
+	}
+
+	pkt := convertToModel(ekPkt)
+	if len(s.extra) > 0 {
+		pkt.Extra = s.extractExtra(ek.Layers)
+	}
+	return pkt
+}
+
+// deliver hands a packet to the consumer and reports false once ctx is done.
+// Live captures never wait for a full channel: stalling here would only push
+// the loss into the kernel where it goes unnoticed, so the packet is dropped
+// and counted instead. Files are read at the consumer's pace.
+func (s *Source) deliver(ctx context.Context, pkt models.PacketData) bool {
+	if s.live {
+		select {
+		case s.out <- pkt:
+			s.packets.Add(1)
+		case <-ctx.Done():
+			return false
+		default:
+			s.pipeDrop.Add(1)
 		}
-	}()
+		return true
+	}
+
+	select {
+	case s.out <- pkt:
+		s.packets.Add(1)
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
 
+// Stop terminates tshark and waits for the supervisor to exit.
+func (s *Source) Stop() error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+	<-s.done
 	return nil
 }
 
+// Packets returns the channel of decoded packets.
+func (s *Source) Packets() <-chan models.PacketData { return s.out }
+
+// Errors returns the channel of capture errors.
+func (s *Source) Errors() <-chan error { return s.errs }
+
+// Stats returns the capture counters.
+func (s *Source) Stats() capture.Stats {
+	st := capture.Stats{
+		Packets:       s.packets.Load(),
+		Restarts:      s.restarts.Load(),
+		KernelDrops:   s.kernelDrop.Load(),
+		PipelineDrops: s.pipeDrop.Load(),
+		Malformed:     s.malformed.Load(),
+		Skipped:       s.skipped.Load(),
+		SampledOut:    s.sampledOut.Load(),
+	}
+	if s.ring.Enabled() {
+		st.RingFile = s.ring.Current()
+	}
+	if s.live {
+		st.KernelDrops += s.interfaceDrops()
+		// Capture buffer drops are only in tshark's exit summary, see readStderr
+		select {
+		case <-s.done:
+		default:
+			st.KernelDropsPending = true
+		}
+	}
+	return st
+}
+
+// interfaceDrops returns the packets the captured interfaces dropped since
+// the capture started. The counters are read while tshark runs, and the last
+// reading is kept once the capture has ended.
+func (s *Source) interfaceDrops() int64 {
+	s.ifDropsMu.Lock()
+	defer s.ifDropsMu.Unlock()
+
+	select {
+	case <-s.done:
+		return s.ifDrops
+	default:
+	}
+	var total int64
+	for iface, base := range s.ifDropsBase {
+		if n, ok := capture.InterfaceDrops(iface); ok && n >= base {
+			total += int64(n - base)
+		}
+	}
+	s.ifDrops = total
+	return total
+}
+
+// readsInterfaceDrops reports whether the drops of an interface are read
+// from its counters, so tshark's own count of them must not be added.
+func (s *Source) readsInterfaceDrops(iface string) bool {
+	s.ifDropsMu.Lock()
+	defer s.ifDropsMu.Unlock()
+
+	_, ok := s.ifDropsBase[iface]
+	return ok
+}
+
+// reportError forwards an error without ever blocking the capture loop.
+func (s *Source) reportError(err error) {
+	select {
+	case s.errs <- err:
+	default:
+	}
+}
+
 func convertToModel(ek EkPacket) *models.PacketData {
-	// We need at least IP info
 	// Check flattened structure fields
-	if len(ek.Layers.IPSrc) == 0 && len(ek.Layers.IPDst) == 0 {
-		return nil
-	}
+	hasIPv4 := len(ek.Layers.IPSrc) > 0 || len(ek.Layers.IPDst) > 0
+	hasIPv6 := len(ek.Layers.IPv6Src) > 0 || len(ek.Layers.IPv6Dst) > 0
 
 	p := &models.PacketData{
-		Timestamp: time.Now(), // Use current time or parse ek.Timestamp
+		Timestamp: packetTime(ek),
 	}
 
 	// Extract Length
@@ -99,17 +442,71 @@ func convertToModel(ek EkPacket) *models.PacketData {
 		}
 	}
 
+	if len(ek.Layers.FrameInterfaceName) > 0 {
+		p.Interface = ek.Layers.FrameInterfaceName[0]
+	}
+
+	// Extract dissection chain, e.g. "eth:ethertype:ip:tcp:tls"
+	if len(ek.Layers.FrameProtocols) > 0 && ek.Layers.FrameProtocols[0] != "" {
+		p.Protocols = strings.Split(ek.Layers.FrameProtocols[0], ":")
+	}
+
+	// Extract Ethernet header
+	if len(ek.Layers.EthSrc) > 0 {
+		p.SrcMAC = ek.Layers.EthSrc[0]
+	}
+	if len(ek.Layers.EthDst) > 0 {
+		p.DstMAC = ek.Layers.EthDst[0]
+	}
+	// For VLAN-tagged frames the payload type is carried by the (innermost) tag
+	etherTypes := ek.Layers.EthType
+	if len(ek.Layers.VLANEtype) > 0 {
+		etherTypes = ek.Layers.VLANEtype
+	}
+	if len(etherTypes) > 0 {
+		if val, err := strconv.ParseUint(etherTypes[len(etherTypes)-1], 0, 16); err == nil {
+			p.EtherType = uint16(val)
+		}
+	}
+	for _, id := range ek.Layers.VLANID {
+		if val, err := strconv.ParseUint(id, 0, 16); err == nil {
+			p.VLANs = append(p.VLANs, uint16(val))
+		}
+	}
+
+	// Non-IP frames (ARP, LLDP, STP, EAPOL...) are still counted.
+	// Their protocol is derived from the EtherType during analysis.
+	if !hasIPv4 && !hasIPv6 {
+		if len(ek.Layers.STPProtocol) > 0 {
+			p.Protocol = "STP"
+		}
+		return p
+	}
+
 	// Extract IP
-	if len(ek.Layers.IPSrc) > 0 {
-		p.SrcIP = ek.Layers.IPSrc[0]
+	// The first occurrence is the outermost header; for 6in4 tunnels that is IPv4.
+	src, dst := ek.Layers.IPSrc, ek.Layers.IPDst
+	p.Family = models.FamilyIPv4
+	if !hasIPv4 {
+		src, dst = ek.Layers.IPv6Src, ek.Layers.IPv6Dst
+		p.Family = models.FamilyIPv6
+	}
+	if len(src) > 0 {
+		p.SrcIP = models.CanonicalIP(src[0])
 	}
-	if len(ek.Layers.IPDst) > 0 {
-		p.DstIP = ek.Layers.IPDst[0]
+	if len(dst) > 0 {
+		p.DstIP = models.CanonicalIP(dst[0])
 	}
 
 	// Extract Ports & Protocol
-	// Check flattened TCP ports
-	if len(ek.Layers.TCPSrcPort) > 0 || len(ek.Layers.TCPDstPort) > 0 {
+	// ICMP comes first: error messages quote the header of the packet that
+	// caused them, whose TCP or UDP ports must not be mistaken for its own.
+	if p.ICMP = convertICMP(ek.Layers); p.ICMP != nil {
+		p.Protocol = "ICMP"
+		if p.ICMP.V6 {
+			p.Protocol = "ICMPv6"
+		}
+	} else if len(ek.Layers.TCPSrcPort) > 0 || len(ek.Layers.TCPDstPort) > 0 {
 		p.Protocol = "TCP"
 		if len(ek.Layers.TCPSrcPort) > 0 {
 			p.SrcPort, _ = strconv.Atoi(ek.Layers.TCPSrcPort[0])
@@ -117,6 +514,7 @@ func convertToModel(ek EkPacket) *models.PacketData {
 		if len(ek.Layers.TCPDstPort) > 0 {
 			p.DstPort, _ = strconv.Atoi(ek.Layers.TCPDstPort[0])
 		}
+		p.TCP = convertTCP(ek.Layers)
 	} else if len(ek.Layers.UDPSrcPort) > 0 || len(ek.Layers.UDPDstPort) > 0 {
 		p.Protocol = "UDP"
 		if len(ek.Layers.UDPSrcPort) > 0 {
@@ -126,9 +524,67 @@ func convertToModel(ek EkPacket) *models.PacketData {
 			p.DstPort, _ = strconv.Atoi(ek.Layers.UDPDstPort[0])
 		}
 	} else {
-		// Might be ICMP or other IP protocol
+		// Some other IP protocol
 		p.Protocol = "OTHER"
 	}
 
+	applyTunnel(p, ek.Layers)
+
+	// tshark also dissects the packet quoted by an ICMP error, which must not
+	// be counted again as traffic of its own. The native backend stops at the
+	// ICMP header.
+	if p.ICMP != nil && p.ICMP.QuotesPacket() {
+		return p
+	}
+
+	inner := p.Inner()
+	p.DNS = convertDNS(ek.Layers, inner.SrcPort, inner.DstPort)
+	p.TLS = convertTLS(ek.Layers)
+	p.HTTP = convertHTTP(ek.Layers)
+	p.QUIC = convertQUIC(ek.Layers)
+	p.Names = convertNames(ek.Layers, p.Protocols)
+
 	return p
 }
+
+// packetTime returns the capture time of the packet. frame.time_epoch carries
+// full precision; the EK timestamp (milliseconds) is used as a fallback.
+// Wall-clock time is only used if tshark provided neither.
+func packetTime(ek EkPacket) time.Time {
+	if len(ek.Layers.FrameTimeEpoch) > 0 {
+		if t, ok := parseEpoch(ek.Layers.FrameTimeEpoch[0]); ok {
+			return t
+		}
+	}
+	if ms, err := strconv.ParseInt(string(ek.Timestamp), 10, 64); err == nil {
+		return time.UnixMilli(ms)
+	}
+	return time.Now()
+}
+
+// parseEpoch parses "seconds.fraction" without going through float64,
+// which would lose nanosecond precision. Newer tshark versions may render
+// absolute times as RFC 3339 in EK output, so that form is accepted too.
+func parseEpoch(s string) (time.Time, bool) {
+	secStr, fracStr, _ := strings.Cut(s, ".")
+	sec, err := strconv.ParseInt(secStr, 10, 64)
+	if err != nil {
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return t, true
+		}
+		return time.Time{}, false
+	}
+
+	var nsec int64
+	if fracStr != "" {
+		if len(fracStr) > 9 {
+			fracStr = fracStr[:9]
+		}
+		fracStr += strings.Repeat("0", 9-len(fracStr))
+		nsec, err = strconv.ParseInt(fracStr, 10, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+	}
+	return time.Unix(sec, nsec), true
+}
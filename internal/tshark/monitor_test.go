@@ -0,0 +1,31 @@
+package tshark
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseEpoch(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   time.Time
+		wantOK bool
+	}{
+		{"1700000000.123456789", time.Unix(1700000000, 123456789), true},
+		{"1700000000.5", time.Unix(1700000000, 500000000), true},
+		{"1700000000.1234567891234", time.Unix(1700000000, 123456789), true},
+		{"1700000000", time.Unix(1700000000, 0), true},
+		{"2023-11-14T22:13:20.25Z", time.Unix(1700000000, 250000000), true},
+		{"1700000000.12x", time.Time{}, false},
+		{"yesterday", time.Time{}, false},
+		{"", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, ok := parseEpoch(tt.in)
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Errorf("parseEpoch(%q) = %v, %v, want %v, %v", tt.in, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
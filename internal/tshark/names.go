@@ -0,0 +1,155 @@
+package tshark
+
+import (
+	"gonetwatch/internal/models"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DHCP message types a host name or address binding is taken from.
+const (
+	dhcpDiscover = 1
+	dhcpRequest  = 3
+	dhcpAck      = 5
+	dhcpInform   = 8
+)
+
+// NetBIOS name service opcodes names are taken from.
+const (
+	nbnsQuery        = 0
+	nbnsRegistration = 5
+	nbnsRefresh      = 8
+	nbnsMultiHomed   = 9
+)
+
+// convertNames extracts the host names a DHCP, mDNS, LLMNR or NetBIOS name
+// service packet announces.
+func convertNames(l EkLayers, protocols []string) []models.HostName {
+	switch {
+	case len(l.DHCPMsgType) > 0:
+		return dhcpNames(l)
+	case slices.Contains(protocols, "mdns"):
+		return dnsNames(l, models.NameMDNS)
+	case slices.Contains(protocols, "llmnr"):
+		return dnsNames(l, models.NameLLMNR)
+	case len(l.NBNSName) > 0:
+		return nbnsNames(l)
+	}
+	return nil
+}
+
+// dhcpNames returns the host name and client identifier of a DHCP client
+// message, or the address a DHCP ACK binds to the client's MAC.
+func dhcpNames(l EkLayers) []models.HostName {
+	if len(l.DHCPMAC) == 0 {
+		return nil
+	}
+	h := models.HostName{Source: models.NameDHCP, MAC: strings.ToLower(l.DHCPMAC[0])}
+	if len(l.DHCPHostname) > 0 {
+		h.Name = l.DHCPHostname[0]
+	}
+	if len(l.DHCPClientID) > 0 {
+		h.ClientID = hexBytes(l.DHCPClientID[0])
+	}
+	clientIP := addressOf(l.DHCPClientIP)
+
+	msgType, _ := strconv.Atoi(nth(l.DHCPMsgType, 0))
+	switch msgType {
+	case dhcpDiscover, dhcpRequest, dhcpInform:
+		// Only a client renewing or informing already holds its address
+		h.IP = clientIP
+	case dhcpAck:
+		h.IP = addressOf(l.DHCPYourIP)
+		if h.IP == "" {
+			h.IP = clientIP // ACK to an INFORM
+		}
+	default:
+		return nil
+	}
+	if h.Name == "" && h.IP == "" && h.ClientID == "" {
+		return nil
+	}
+	return []models.HostName{h}
+}
+
+// dnsNames returns the addresses an mDNS or LLMNR response gives for host
+// names. The names, types and addresses of the records come as separate
+// lists, so the addresses are matched to the A and AAAA records in order.
+func dnsNames(l EkLayers, source models.NameSource) []models.HostName {
+	if len(l.DNSResponse) == 0 || !parseFlag(l.DNSResponse[0]) {
+		return nil
+	}
+	var names []models.HostName
+	a, aaaa := 0, 0
+	for i, name := range l.DNSRespName {
+		if i >= len(l.DNSRespType) {
+			break
+		}
+		var ip string
+		switch l.DNSRespType[i] {
+		case "1":
+			if a < len(l.DNSA) {
+				ip = l.DNSA[a]
+			}
+			a++
+		case "28":
+			if aaaa < len(l.DNSAAAA) {
+				ip = l.DNSAAAA[aaaa]
+			}
+			aaaa++
+		default:
+			continue
+		}
+		name = strings.TrimSuffix(strings.TrimSuffix(name, "."), ".local")
+		if name != "" && ip != "" {
+			names = append(names, models.HostName{Source: source, Name: name, IP: models.CanonicalIP(ip)})
+		}
+	}
+	return names
+}
+
+// nbnsNames returns the unique names a NetBIOS name service packet binds to
+// addresses: those of registrations and refreshes, and of positive answers to
+// name queries. tshark renders names with their service suffix, e.g.
+// "WORKSTATION<20>"; only the workstation and server services are kept.
+func nbnsNames(l EkLayers) []models.HostName {
+	response := len(l.NBNSResponse) > 0 && parseFlag(l.NBNSResponse[0])
+	opcode, _ := strconv.Atoi(nth(l.NBNSOpcode, 0))
+	rcode, _ := strconv.Atoi(nth(l.NBNSRCode, 0))
+	switch opcode {
+	case nbnsRegistration, nbnsRefresh, nbnsMultiHomed:
+		if response {
+			return nil
+		}
+	case nbnsQuery:
+		if !response || rcode != 0 {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	name, suffix, ok := strings.Cut(l.NBNSName[0], "<")
+	name = strings.TrimRight(name, " ")
+	if !ok || name == "" || name == "*" || !(strings.HasPrefix(suffix, "00>") || strings.HasPrefix(suffix, "20>")) {
+		return nil
+	}
+
+	var names []models.HostName
+	for i, addr := range l.NBNSAddr {
+		if i < len(l.NBNSGroup) && parseFlag(l.NBNSGroup[i]) {
+			continue // Workgroup or domain name
+		}
+		names = append(names, models.HostName{Source: models.NameNBNS, Name: name, IP: addr})
+	}
+	return names
+}
+
+// addressOf returns the first address of a field, or "" if it is missing or unspecified.
+func addressOf(values []string) string {
+	if addr := nth(values, 0); addr != "0.0.0.0" {
+		return addr
+	}
+	return ""
+}
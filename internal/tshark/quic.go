@@ -0,0 +1,46 @@
+package tshark
+
+import (
+	"gonetwatch/internal/models"
+	"strconv"
+	"strings"
+)
+
+// convertQUIC extracts the header of the first QUIC packet of a datagram, or
+// returns nil if it has none. tshark also decrypts Initial packets, so the
+// ClientHello inside is available through convertTLS.
+func convertQUIC(l EkLayers) *models.QUICInfo {
+	if len(l.QUICHeaderForm) == 0 && len(l.QUICDCID) == 0 {
+		return nil
+	}
+
+	q := &models.QUICInfo{}
+	if len(l.QUICHeaderForm) > 0 {
+		q.LongHeader = parseFlag(l.QUICHeaderForm[0])
+	}
+	if q.LongHeader {
+		if len(l.QUICPacketType) > 0 {
+			if v, err := strconv.ParseUint(l.QUICPacketType[0], 0, 8); err == nil {
+				q.PacketType = uint8(v)
+			}
+		}
+		if len(l.QUICVersion) > 0 {
+			if v, err := strconv.ParseUint(l.QUICVersion[0], 0, 32); err == nil {
+				q.Version = uint32(v)
+			}
+		}
+		if len(l.QUICSCID) > 0 {
+			q.SCID = hexBytes(l.QUICSCID[0])
+		}
+	}
+	if len(l.QUICDCID) > 0 {
+		q.DCID = hexBytes(l.QUICDCID[0])
+	}
+	return q
+}
+
+// hexBytes normalizes a byte field, which tshark renders with or without
+// colons depending on version, to lowercase hex.
+func hexBytes(s string) string {
+	return strings.ToLower(strings.ReplaceAll(s, ":", ""))
+}
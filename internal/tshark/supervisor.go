@@ -0,0 +1,185 @@
+package tshark
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+	// stableRun is how long tshark must stay up before the backoff is reset.
+	stableRun = 30 * time.Second
+	// stopGrace is how long tshark gets to exit after being interrupted
+	// before it is killed.
+	stopGrace = 3 * time.Second
+)
+
+// supervise runs tshark until ctx is cancelled. Live captures are restarted
+// with exponential backoff whenever tshark exits; a file capture ends once
+// tshark has read the whole file.
+func (s *Source) supervise(ctx context.Context) {
+	defer close(s.done)
+	defer close(s.out)
+	defer s.interfaceDrops() // Last reading before the capture counts as ended
+
+	backoff := minBackoff
+	for {
+		started := time.Now()
+		runCtx, runCancel := context.WithCancel(ctx)
+		s.mu.Lock()
+		s.runCancel = runCancel
+		s.mu.Unlock()
+
+		err := s.runOnce(runCtx)
+		runCancel()
+
+		// Every tshark run numbers its ring buffer files afresh and only
+		// prunes its own, so the limit is enforced across runs here
+		if s.ring.Enabled() {
+			if err := s.ring.Prune(); err != nil {
+				s.reportError(fmt.Errorf("failed to prune ring buffer: %v", err))
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Stopped on purpose to apply new filters: restart right away
+		s.mu.Lock()
+		restartNow := s.restartNow
+		s.restartNow = false
+		s.mu.Unlock()
+		if restartNow {
+			continue
+		}
+
+		if !s.live {
+			if err != nil {
+				s.reportError(fmt.Errorf("tshark failed reading file: %v", err))
+			}
+			return
+		}
+
+		if time.Since(started) > stableRun {
+			backoff = minBackoff
+		}
+		if err == nil {
+			err = errors.New("exited unexpectedly")
+		}
+		s.reportError(fmt.Errorf("tshark %v; restarting in %s", err, backoff))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		s.restarts.Add(1)
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// runOnce starts a single tshark process and blocks until it exits.
+func (s *Source) runOnce(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "tshark", s.commandArgs()...)
+	// Interrupt rather than kill so tshark can flush and print its summary.
+	// Where interrupts are unsupported, WaitDelay falls back to killing it.
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = stopGrace
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to get stdout pipe: %v", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to get stderr pipe: %v", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start: %v", err)
+	}
+
+	// Both pipes must be drained before Wait closes them
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.readStderr(stderr)
+	}()
+
+	s.decode(ctx, stdout)
+	wg.Wait()
+
+	return cmd.Wait()
+}
+
+// readStderr surfaces tshark's diagnostics as capture errors instead of
+// letting them draw over the TUI. Routine status lines are dropped, and the
+// drop counts tshark prints when it exits are added to the kernel drops.
+func (s *Source) readStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || isStatusLine(line) {
+			continue
+		}
+		if d, ok := parseDropLine(line); ok {
+			// Interface drops are counted from the interface counters instead
+			if !d.byInterface || !s.readsInterfaceDrops(d.iface) {
+				s.kernelDrop.Add(d.count)
+			}
+			continue
+		}
+		s.reportError(fmt.Errorf("tshark: %s", line))
+	}
+}
+
+// dropLine is a line of tshark's exit summary of dropped packets.
+type dropLine struct {
+	count       int64
+	iface       string
+	byInterface bool // Dropped by the interface rather than from the capture buffer
+}
+
+// parseDropLine recognizes tshark's exit summary of dropped packets, e.g.
+// "12 packets dropped from eth0" or "1 packet dropped by interface eth0".
+func parseDropLine(line string) (dropLine, bool) {
+	count, rest, ok := strings.Cut(line, " ")
+	if !ok {
+		return dropLine{}, false
+	}
+	rest, ok = strings.CutPrefix(rest, "packets dropped ")
+	if !ok {
+		if rest, ok = strings.CutPrefix(rest, "packet dropped "); !ok {
+			return dropLine{}, false
+		}
+	}
+	n, err := strconv.ParseInt(count, 10, 64)
+	if err != nil {
+		return dropLine{}, false
+	}
+	d := dropLine{count: n}
+	if iface, ok := strings.CutPrefix(rest, "by interface "); ok {
+		d.iface, d.byInterface = iface, true
+	} else {
+		d.iface = strings.TrimPrefix(rest, "from ")
+	}
+	return d, true
+}
+
+func isStatusLine(line string) bool {
+	return strings.HasPrefix(line, "Capturing on ") ||
+		strings.HasPrefix(line, "Running as user ") ||
+		strings.HasSuffix(line, " packets captured") ||
+		strings.HasSuffix(line, " packet captured")
+}
@@ -0,0 +1,26 @@
+package tshark
+
+import (
+	"gonetwatch/internal/models"
+	"strconv"
+)
+
+// convertTCP extracts the TCP flags and tshark's sequence analysis of a packet.
+// The analysis fields carry no value; their presence marks the condition.
+func convertTCP(l EkLayers) *models.TCPInfo {
+	t := &models.TCPInfo{
+		Retransmission: len(l.TCPRetransmission) > 0,
+		DuplicateAck:   len(l.TCPDuplicateAck) > 0,
+		ZeroWindow:     len(l.TCPZeroWindow) > 0,
+	}
+	if len(l.TCPFlags) > 0 {
+		// Rendered in hex, e.g. "0x0012"
+		if flags, err := strconv.ParseUint(l.TCPFlags[0], 0, 16); err == nil {
+			t.Flags = uint16(flags)
+		}
+	}
+	if len(l.TCPWindow) > 0 {
+		t.Window, _ = strconv.Atoi(l.TCPWindow[0])
+	}
+	return t
+}
@@ -0,0 +1,48 @@
+package tshark
+
+import (
+	"gonetwatch/internal/models"
+	"slices"
+	"strconv"
+)
+
+// convertTLS extracts the ClientHello or ServerHello of a packet, or returns
+// nil if it has neither. Other handshake messages are ignored.
+func convertTLS(l EkLayers) *models.TLSInfo {
+	var htype uint8
+	for _, t := range l.TLSHandshakeType {
+		if n, err := strconv.Atoi(t); err == nil && (n == models.TLSClientHello || n == models.TLSServerHello) {
+			htype = uint8(n)
+			break
+		}
+	}
+	if htype == 0 {
+		return nil
+	}
+
+	t := &models.TLSInfo{HandshakeType: htype}
+	if len(l.TLSServerName) > 0 {
+		t.ServerName = l.TLSServerName[0]
+	}
+	t.ALPN = slices.Clone(l.TLSALPN)
+	if len(l.TLSJA3) > 0 {
+		t.JA3 = l.TLSJA3[0]
+	}
+	if len(l.TLSJA4) > 0 {
+		t.JA4 = l.TLSJA4[0]
+	}
+
+	// The supported_versions extension supersedes the legacy version field.
+	// A ClientHello lists every version it offers; the highest one counts.
+	for _, v := range l.TLSSupportedVer {
+		if n, err := strconv.ParseUint(v, 0, 16); err == nil && !models.IsGREASE(uint16(n)) {
+			t.Version = max(t.Version, uint16(n))
+		}
+	}
+	if t.Version == 0 && len(l.TLSVersion) > 0 {
+		if n, err := strconv.ParseUint(l.TLSVersion[0], 0, 16); err == nil {
+			t.Version = uint16(n)
+		}
+	}
+	return t
+}
@@ -0,0 +1,133 @@
+package tshark
+
+import (
+	"gonetwatch/internal/models"
+	"strconv"
+)
+
+// tunnelDissectors maps the dissectors of supported encapsulations to their tunnel type.
+var tunnelDissectors = map[string]string{
+	"gre":    models.TunnelGRE,
+	"vxlan":  models.TunnelVXLAN,
+	"geneve": models.TunnelGENEVE,
+}
+
+// layerRef is one occurrence of a dissector in frame.protocols. Its index
+// selects the matching occurrence of the dissector's fields.
+type layerRef struct {
+	name  string
+	index int
+}
+
+// applyTunnel splits the headers of an encapsulated packet at the tunnel
+// found in its dissection chain: the addressing fields get the outer
+// headers and the tunnel info the inner ones. The first occurrence of a
+// field is not enough here, since e.g. the TCP ports of a VXLAN packet
+// belong to the inner packet while its outer transport is UDP.
+func applyTunnel(p *models.PacketData, l EkLayers) {
+	var outer, inner []layerRef
+	var tunnel string
+	outerVLANs := 0
+	seen := make(map[string]int)
+	for _, name := range p.Protocols {
+		ref := layerRef{name, seen[name]}
+		seen[name]++
+
+		if t, ok := tunnelDissectors[name]; ok && tunnel == "" {
+			tunnel = t
+			continue
+		}
+		switch name {
+		case "vlan":
+			if tunnel == "" {
+				outerVLANs++
+			}
+		case "ip", "ipv6", "tcp", "udp", "icmp", "icmpv6":
+			if tunnel == "" {
+				outer = append(outer, ref)
+			} else {
+				inner = append(inner, ref)
+			}
+		}
+	}
+	if tunnel == "" {
+		return
+	}
+
+	t := &models.TunnelInfo{Type: tunnel}
+	var vni []string
+	switch tunnel {
+	case models.TunnelVXLAN:
+		vni = l.VXLANVNI
+	case models.TunnelGENEVE:
+		vni = l.GENEVEVNI
+	case models.TunnelGRE:
+		vni = l.GREKey
+	}
+	if len(vni) > 0 {
+		if val, err := strconv.ParseUint(vni[0], 0, 32); err == nil {
+			t.VNI = uint32(val)
+		}
+	}
+	t.Family, t.SrcIP, t.DstIP, t.Protocol, t.SrcPort, t.DstPort = l.addressing(inner)
+	p.Tunnel = t
+
+	p.Family, p.SrcIP, p.DstIP, p.Protocol, p.SrcPort, p.DstPort = l.addressing(outer)
+	if len(p.VLANs) > outerVLANs {
+		p.VLANs = p.VLANs[:outerVLANs]
+	}
+
+	// The transport details belong to the inner packet
+	p.TCP, p.ICMP = nil, nil
+	switch t.Protocol {
+	case "TCP":
+		p.TCP = convertTCP(l)
+	case "ICMP", "ICMPv6":
+		p.ICMP = convertICMP(l)
+	}
+}
+
+// addressing returns the addressing of the first network header among refs
+// and of the transport header following it.
+func (l EkLayers) addressing(refs []layerRef) (family models.AddressFamily, src, dst, proto string, srcPort, dstPort int) {
+	for i, ref := range refs {
+		switch ref.name {
+		case "ip":
+			family, src, dst = models.FamilyIPv4, nth(l.IPSrc, ref.index), nth(l.IPDst, ref.index)
+		case "ipv6":
+			family, src, dst = models.FamilyIPv6, nth(l.IPv6Src, ref.index), nth(l.IPv6Dst, ref.index)
+		default:
+			continue
+		}
+		src, dst = models.CanonicalIP(src), models.CanonicalIP(dst)
+
+		proto = "OTHER"
+		if i+1 < len(refs) {
+			next := refs[i+1]
+			switch next.name {
+			case "tcp":
+				proto = "TCP"
+				srcPort, _ = strconv.Atoi(nth(l.TCPSrcPort, next.index))
+				dstPort, _ = strconv.Atoi(nth(l.TCPDstPort, next.index))
+			case "udp":
+				proto = "UDP"
+				srcPort, _ = strconv.Atoi(nth(l.UDPSrcPort, next.index))
+				dstPort, _ = strconv.Atoi(nth(l.UDPDstPort, next.index))
+			case "icmp":
+				proto = "ICMP"
+			case "icmpv6":
+				proto = "ICMPv6"
+			}
+		}
+		return
+	}
+	return
+}
+
+// nth returns the i-th occurrence of a field, or "" if there are fewer.
+func nth(values []string, i int) string {
+	if i < len(values) {
+		return values[i]
+	}
+	return ""
+}
@@ -1,19 +1,269 @@
 package tshark
 
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ekLine is one line of tshark -T ek output: either a bulk index line
+// ({"index":{...}}) or a packet line ({"timestamp":...,"layers":{...}}).
+type ekLine struct {
+	Index     json.RawMessage `json:"index"`
+	Timestamp EkTimestamp     `json:"timestamp"`
+	Layers    json.RawMessage `json:"layers"`
+}
+
 // EkPacket represents the top-level structure of a Tshark -T ek output line.
 type EkPacket struct {
-	Timestamp string   `json:"timestamp"`
-	Layers    EkLayers `json:"layers"`
+	Timestamp EkTimestamp `json:"timestamp"`
+	Layers    EkLayers    `json:"layers"`
+}
+
+// EkTimestamp is the packet time in milliseconds since the epoch.
+// Depending on the tshark version it is encoded as a JSON string or number.
+type EkTimestamp string
+
+// UnmarshalJSON accepts both the quoted and the bare numeric form.
+func (t *EkTimestamp) UnmarshalJSON(data []byte) error {
+	*t = EkTimestamp(strings.Trim(string(data), `"`))
+	return nil
 }
 
 // EkLayers holds the specific protocol layers we are interested in.
 // When using -e flags with -T ek, tshark flattens the structure and replaces dots with underscores.
 type EkLayers struct {
-	FrameLen   []string `json:"frame_len,omitempty"`
-	IPSrc      []string `json:"ip_src,omitempty"`
-	IPDst      []string `json:"ip_dst,omitempty"`
-	TCPSrcPort []string `json:"tcp_srcport,omitempty"`
-	TCPDstPort []string `json:"tcp_dstport,omitempty"`
-	UDPSrcPort []string `json:"udp_srcport,omitempty"`
-	UDPDstPort []string `json:"udp_dstport,omitempty"`
+	FrameLen           []string `json:"frame_len,omitempty"`
+	FrameTimeEpoch     []string `json:"frame_time_epoch,omitempty"`
+	FrameProtocols     []string `json:"frame_protocols,omitempty"`
+	FrameInterfaceName []string `json:"frame_interface_name,omitempty"`
+	EthSrc             []string `json:"eth_src,omitempty"`
+	EthDst             []string `json:"eth_dst,omitempty"`
+	EthType            []string `json:"eth_type,omitempty"`
+	VLANEtype          []string `json:"vlan_etype,omitempty"`
+	STPProtocol        []string `json:"stp_protocol,omitempty"`
+	IPSrc              []string `json:"ip_src,omitempty"`
+	IPDst              []string `json:"ip_dst,omitempty"`
+	IPv6Src            []string `json:"ipv6_src,omitempty"`
+	IPv6Dst            []string `json:"ipv6_dst,omitempty"`
+	TCPSrcPort         []string `json:"tcp_srcport,omitempty"`
+	TCPDstPort         []string `json:"tcp_dstport,omitempty"`
+	TCPFlags           []string `json:"tcp_flags,omitempty"`
+	TCPWindow          []string `json:"tcp_window_size_value,omitempty"`
+	TCPRetransmission  []string `json:"tcp_analysis_retransmission,omitempty"`
+	TCPDuplicateAck    []string `json:"tcp_analysis_duplicate_ack,omitempty"`
+	TCPZeroWindow      []string `json:"tcp_analysis_zero_window,omitempty"`
+	ICMPType           []string `json:"icmp_type,omitempty"`
+	ICMPCode           []string `json:"icmp_code,omitempty"`
+	ICMPIdent          []string `json:"icmp_ident,omitempty"`
+	ICMPSeq            []string `json:"icmp_seq,omitempty"`
+	ICMPMTU            []string `json:"icmp_mtu,omitempty"`
+	ICMPv6Type         []string `json:"icmpv6_type,omitempty"`
+	ICMPv6Code         []string `json:"icmpv6_code,omitempty"`
+	ICMPv6Ident        []string `json:"icmpv6_echo_identifier,omitempty"`
+	ICMPv6Seq          []string `json:"icmpv6_echo_sequence_number,omitempty"`
+	ICMPv6MTU          []string `json:"icmpv6_mtu,omitempty"`
+	VLANID             []string `json:"vlan_id,omitempty"`
+	VXLANVNI           []string `json:"vxlan_vni,omitempty"`
+	GENEVEVNI          []string `json:"geneve_vni,omitempty"`
+	GREKey             []string `json:"gre_key,omitempty"`
+	QUICHeaderForm     []string `json:"quic_header_form,omitempty"`
+	QUICPacketType     []string `json:"quic_long_packet_type,omitempty"`
+	QUICVersion        []string `json:"quic_version,omitempty"`
+	QUICDCID           []string `json:"quic_dcid,omitempty"`
+	QUICSCID           []string `json:"quic_scid,omitempty"`
+	UDPSrcPort         []string `json:"udp_srcport,omitempty"`
+	UDPDstPort         []string `json:"udp_dstport,omitempty"`
+	DNSID              []string `json:"dns_id,omitempty"`
+	DNSResponse        []string `json:"dns_flags_response,omitempty"`
+	DNSRCode           []string `json:"dns_flags_rcode,omitempty"`
+	DNSQryName         []string `json:"dns_qry_name,omitempty"`
+	DNSQryType         []string `json:"dns_qry_type,omitempty"`
+	DNSA               []string `json:"dns_a,omitempty"`
+	DNSAAAA            []string `json:"dns_aaaa,omitempty"`
+	DNSCNAME           []string `json:"dns_cname,omitempty"`
+	DNSRespName        []string `json:"dns_resp_name,omitempty"`
+	DNSRespType        []string `json:"dns_resp_type,omitempty"`
+	DHCPMsgType        []string `json:"dhcp_option_dhcp,omitempty"`
+	DHCPHostname       []string `json:"dhcp_option_hostname,omitempty"`
+	DHCPClientID       []string `json:"dhcp_client_id,omitempty"`
+	DHCPMAC            []string `json:"dhcp_hw_mac_addr,omitempty"`
+	DHCPClientIP       []string `json:"dhcp_ip_client,omitempty"`
+	DHCPYourIP         []string `json:"dhcp_ip_your,omitempty"`
+	NBNSResponse       []string `json:"nbns_flags_response,omitempty"`
+	NBNSOpcode         []string `json:"nbns_flags_opcode,omitempty"`
+	NBNSRCode          []string `json:"nbns_flags_rcode,omitempty"`
+	NBNSName           []string `json:"nbns_name,omitempty"`
+	NBNSAddr           []string `json:"nbns_addr,omitempty"`
+	NBNSGroup          []string `json:"nbns_nb_flags_group,omitempty"`
+	TLSHandshakeType   []string `json:"tls_handshake_type,omitempty"`
+	TLSServerName      []string `json:"tls_handshake_extensions_server_name,omitempty"`
+	TLSALPN            []string `json:"tls_handshake_extensions_alpn_str,omitempty"`
+	TLSVersion         []string `json:"tls_handshake_version,omitempty"`
+	TLSSupportedVer    []string `json:"tls_handshake_extensions_supported_version,omitempty"`
+	TLSJA3             []string `json:"tls_handshake_ja3_hash,omitempty"`
+	TLSJA4             []string `json:"tls_handshake_ja4,omitempty"`
+	HTTPMethod         []string `json:"http_request_method,omitempty"`
+	HTTPHost           []string `json:"http_host,omitempty"`
+	HTTPURI            []string `json:"http_request_uri,omitempty"`
+	HTTPUserAgent      []string `json:"http_user_agent,omitempty"`
+	HTTPStatus         []string `json:"http_response_code,omitempty"`
+}
+
+// slot returns the EkLayers member holding a tshark field, or nil if the
+// field is not one of them. The fields output format has no keys, so its
+// columns are mapped back onto EkLayers through this.
+func (l *EkLayers) slot(field string) *[]string {
+	switch field {
+	case "frame.len":
+		return &l.FrameLen
+	case "frame.time_epoch":
+		return &l.FrameTimeEpoch
+	case "frame.protocols":
+		return &l.FrameProtocols
+	case "frame.interface_name":
+		return &l.FrameInterfaceName
+	case "eth.src":
+		return &l.EthSrc
+	case "eth.dst":
+		return &l.EthDst
+	case "eth.type":
+		return &l.EthType
+	case "vlan.etype":
+		return &l.VLANEtype
+	case "stp.protocol":
+		return &l.STPProtocol
+	case "ip.src":
+		return &l.IPSrc
+	case "ip.dst":
+		return &l.IPDst
+	case "ipv6.src":
+		return &l.IPv6Src
+	case "ipv6.dst":
+		return &l.IPv6Dst
+	case "tcp.srcport":
+		return &l.TCPSrcPort
+	case "tcp.dstport":
+		return &l.TCPDstPort
+	case "tcp.flags":
+		return &l.TCPFlags
+	case "tcp.window_size_value":
+		return &l.TCPWindow
+	case "tcp.analysis.retransmission":
+		return &l.TCPRetransmission
+	case "tcp.analysis.duplicate_ack":
+		return &l.TCPDuplicateAck
+	case "tcp.analysis.zero_window":
+		return &l.TCPZeroWindow
+	case "icmp.type":
+		return &l.ICMPType
+	case "icmp.code":
+		return &l.ICMPCode
+	case "icmp.ident":
+		return &l.ICMPIdent
+	case "icmp.seq":
+		return &l.ICMPSeq
+	case "icmp.mtu":
+		return &l.ICMPMTU
+	case "icmpv6.type":
+		return &l.ICMPv6Type
+	case "icmpv6.code":
+		return &l.ICMPv6Code
+	case "icmpv6.echo.identifier":
+		return &l.ICMPv6Ident
+	case "icmpv6.echo.sequence_number":
+		return &l.ICMPv6Seq
+	case "icmpv6.mtu":
+		return &l.ICMPv6MTU
+	case "vlan.id":
+		return &l.VLANID
+	case "vxlan.vni":
+		return &l.VXLANVNI
+	case "geneve.vni":
+		return &l.GENEVEVNI
+	case "gre.key":
+		return &l.GREKey
+	case "quic.header_form":
+		return &l.QUICHeaderForm
+	case "quic.long.packet_type":
+		return &l.QUICPacketType
+	case "quic.version":
+		return &l.QUICVersion
+	case "quic.dcid":
+		return &l.QUICDCID
+	case "quic.scid":
+		return &l.QUICSCID
+	case "udp.srcport":
+		return &l.UDPSrcPort
+	case "udp.dstport":
+		return &l.UDPDstPort
+	case "dns.id":
+		return &l.DNSID
+	case "dns.flags.response":
+		return &l.DNSResponse
+	case "dns.flags.rcode":
+		return &l.DNSRCode
+	case "dns.qry.name":
+		return &l.DNSQryName
+	case "dns.qry.type":
+		return &l.DNSQryType
+	case "dns.a":
+		return &l.DNSA
+	case "dns.aaaa":
+		return &l.DNSAAAA
+	case "dns.cname":
+		return &l.DNSCNAME
+	case "dns.resp.name":
+		return &l.DNSRespName
+	case "dns.resp.type":
+		return &l.DNSRespType
+	case "dhcp.option.dhcp":
+		return &l.DHCPMsgType
+	case "dhcp.option.hostname":
+		return &l.DHCPHostname
+	case "dhcp.client_id":
+		return &l.DHCPClientID
+	case "dhcp.hw.mac_addr":
+		return &l.DHCPMAC
+	case "dhcp.ip.client":
+		return &l.DHCPClientIP
+	case "dhcp.ip.your":
+		return &l.DHCPYourIP
+	case "nbns.flags.response":
+		return &l.NBNSResponse
+	case "nbns.flags.opcode":
+		return &l.NBNSOpcode
+	case "nbns.flags.rcode":
+		return &l.NBNSRCode
+	case "nbns.name":
+		return &l.NBNSName
+	case "nbns.addr":
+		return &l.NBNSAddr
+	case "nbns.nb_flags.group":
+		return &l.NBNSGroup
+	case "tls.handshake.type":
+		return &l.TLSHandshakeType
+	case "tls.handshake.extensions_server_name":
+		return &l.TLSServerName
+	case "tls.handshake.extensions_alpn_str":
+		return &l.TLSALPN
+	case "tls.handshake.version":
+		return &l.TLSVersion
+	case "tls.handshake.extensions.supported_version":
+		return &l.TLSSupportedVer
+	case "tls.handshake.ja3_hash":
+		return &l.TLSJA3
+	case "tls.handshake.ja4":
+		return &l.TLSJA4
+	case "http.request.method":
+		return &l.HTTPMethod
+	case "http.host":
+		return &l.HTTPHost
+	case "http.request.uri":
+		return &l.HTTPURI
+	case "http.user_agent":
+		return &l.HTTPUserAgent
+	case "http.response.code":
+		return &l.HTTPStatus
+	}
+	return nil
 }
@@ -0,0 +1,80 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// dnsView renders the DNS activity panels.
+func (m AnalysisModel) dnsView() string {
+	d := m.dns
+	if d.Queries == 0 && d.Responses == 0 {
+		return infoStyle.Render("DNS:\nWaiting for DNS traffic...")
+	}
+
+	summary := fmt.Sprintf("Queries: %d\nResponses: %d\nNXDOMAIN: %d (%.1f%%)",
+		d.Queries, d.Responses, d.NXDomain, d.NXDomainRate)
+	summaryBox := infoStyle.Render("DNS:\n" + summary)
+
+	var domainStrs []string
+	for _, dom := range d.TopDomains {
+		line := fmt.Sprintf("%-40s %6d q", truncateText(dom.Name, 40), dom.Queries)
+		if dom.NXDomain > 0 {
+			line += fmt.Sprintf(", %d NX", dom.NXDomain)
+		}
+		domainStrs = append(domainStrs, line)
+	}
+	domainBox := infoStyle.Render("Top Queried Domains:\n" + orWaiting(domainStrs))
+
+	var resolverStrs []string
+	for _, r := range d.Resolvers {
+		line := fmt.Sprintf("%-39s avg %s, max %s, %d resp",
+			m.hostLabel(r.IP, 39), formatLatency(r.AvgLatency), formatLatency(r.MaxLatency), r.Responses)
+		if r.Failures > 0 {
+			line += fmt.Sprintf(", %d failed", r.Failures)
+		}
+		resolverStrs = append(resolverStrs, line)
+	}
+	resolverBox := infoStyle.Render("Slowest Resolvers:\n" + orWaiting(resolverStrs))
+
+	var clientStrs []string
+	for _, c := range d.Clients {
+		line := fmt.Sprintf("%-39s %6d q", m.hostLabel(c.IP, 39), c.Queries)
+		if c.NXDomain > 0 {
+			line += fmt.Sprintf(", %d NX", c.NXDomain)
+		}
+		clientStrs = append(clientStrs, line)
+	}
+	clientBox := infoStyle.Render("Clients by Query Volume:\n" + orWaiting(clientStrs))
+
+	row1 := lipgloss.JoinHorizontal(lipgloss.Top, summaryBox, domainBox)
+	row2 := lipgloss.JoinHorizontal(lipgloss.Top, resolverBox, clientBox)
+	return lipgloss.JoinVertical(lipgloss.Left, row1, row2)
+}
+
+// orWaiting joins panel lines, or returns a placeholder if there are none.
+func orWaiting(lines []string) string {
+	if len(lines) == 0 {
+		return "Waiting for data..."
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncateText shortens s to n characters, marking the cut with an ellipsis.
+func truncateText(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+func formatLatency(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return d.Round(100 * time.Microsecond).String()
+}
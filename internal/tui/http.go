@@ -0,0 +1,55 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// httpView renders the cleartext HTTP activity panels.
+func (m AnalysisModel) httpView() string {
+	h := m.http
+	if h.Requests == 0 && h.Responses == 0 {
+		return infoStyle.Render("HTTP:\nWaiting for cleartext HTTP traffic...")
+	}
+
+	var codes []string
+	for _, c := range h.StatusCodes {
+		codes = append(codes, fmt.Sprintf("%d: %d", c.Code, c.Count))
+	}
+	summary := fmt.Sprintf("Requests: %d\nResponses: %d\n4xx: %d  5xx: %d (%.1f%%)\n%s",
+		h.Requests, h.Responses, h.ClientErrors, h.ServerErrors, h.ErrorRate, strings.Join(codes, "\n"))
+	summaryBox := infoStyle.Render("HTTP:\n" + summary)
+
+	var hostStrs []string
+	for _, host := range h.Hosts {
+		line := fmt.Sprintf("%-32s %6d req", truncateText(host.Host, 32), host.Requests)
+		if host.Responses > 0 {
+			line += fmt.Sprintf(", %5.1f%% err, avg %s", host.ErrorRate, formatLatency(host.AvgTime))
+		}
+		hostStrs = append(hostStrs, line)
+	}
+	hostBox := infoStyle.Render("Top Hosts:\n" + orWaiting(hostStrs))
+
+	var endpointStrs []string
+	for _, e := range h.Endpoints {
+		name := e.Method + " " + e.Host + e.Path
+		line := fmt.Sprintf("%-50s avg %s, max %s, %d req",
+			truncateText(name, 50), formatLatency(e.AvgTime), formatLatency(e.MaxTime), e.Requests)
+		if e.Errors > 0 {
+			line += fmt.Sprintf(", %d err", e.Errors)
+		}
+		endpointStrs = append(endpointStrs, line)
+	}
+	endpointBox := infoStyle.Render("Slowest Endpoints:\n" + orWaiting(endpointStrs))
+
+	var uaStrs []string
+	for _, ua := range h.UserAgents {
+		uaStrs = append(uaStrs, fmt.Sprintf("%-40s %6d req", truncateText(ua.UserAgent, 40), ua.Requests))
+	}
+	uaBox := infoStyle.Render("User Agents:\n" + orWaiting(uaStrs))
+
+	row1 := lipgloss.JoinHorizontal(lipgloss.Top, summaryBox, hostBox)
+	return lipgloss.JoinVertical(lipgloss.Left, row1, endpointBox, uaBox)
+}
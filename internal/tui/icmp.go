@@ -0,0 +1,70 @@
+package tui
+
+import (
+	"fmt"
+	"gonetwatch/internal/analysis"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// icmpView renders the ICMP message counts, error sources and ping RTTs.
+func (m AnalysisModel) icmpView() string {
+	c := m.icmp
+	if c.Messages == 0 {
+		return infoStyle.Render("ICMP:\nWaiting for ICMP traffic...")
+	}
+
+	summary := fmt.Sprintf("Messages: %d\nEcho: %d req, %d reply\nUnreachable: %d\nTTL exceeded: %d\nRedirects: %d\nPacket too big: %d",
+		c.Messages, c.EchoRequests, c.EchoReplies, c.Unreachable, c.TimeExceeded, c.Redirects, c.PacketTooBig)
+	summaryBox := infoStyle.Render("ICMP:\n" + summary)
+
+	var typeStrs []string
+	for _, t := range c.Types {
+		typeStrs = append(typeStrs, fmt.Sprintf("%-42s %8d", truncateText(t.Name, 42), t.Count))
+	}
+	typeBox := infoStyle.Render("Message Types:\n" + orWaiting(typeStrs))
+
+	sourceBox := infoStyle.Render("Error Sources:\n" + orWaiting(m.icmpHostLines(c.Sources)))
+	destBox := infoStyle.Render("Error Destinations:\n" + orWaiting(m.icmpHostLines(c.Destinations)))
+
+	var pingStrs []string
+	for _, p := range c.Pings {
+		line := fmt.Sprintf("%-39s %5d req, %5.1f%% loss", m.hostLabel(p.Target, 39), p.Requests, p.Loss)
+		if p.AvgRTT > 0 {
+			line += fmt.Sprintf(", min/avg/max %s/%s/%s",
+				formatLatency(p.MinRTT), formatLatency(p.AvgRTT), formatLatency(p.MaxRTT))
+		}
+		pingStrs = append(pingStrs, line)
+	}
+	pingBox := infoStyle.Render("Ping RTT:\n" + orWaiting(pingStrs))
+
+	row1 := lipgloss.JoinHorizontal(lipgloss.Top, summaryBox, typeBox)
+	row2 := lipgloss.JoinHorizontal(lipgloss.Top, sourceBox, destBox)
+	return lipgloss.JoinVertical(lipgloss.Left, row1, row2, pingBox)
+}
+
+// icmpHostLines formats the error counts of hosts, leaving out zero counts.
+func (m AnalysisModel) icmpHostLines(hosts []analysis.ICMPHostStat) []string {
+	var lines []string
+	for _, h := range hosts {
+		line := fmt.Sprintf("%-39s", m.hostLabel(h.IP, 39))
+		for _, part := range []struct {
+			label string
+			n     int64
+		}{
+			{"unreach", h.Unreachable},
+			{"ttl", h.TimeExceeded},
+			{"redirect", h.Redirects},
+			{"too big", h.PacketTooBig},
+		} {
+			if part.n > 0 {
+				line += fmt.Sprintf(" %d %s", part.n, part.label)
+			}
+		}
+		if h.MinMTU > 0 {
+			line += fmt.Sprintf(" (MTU %d)", h.MinMTU)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
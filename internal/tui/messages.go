@@ -5,3 +5,21 @@ import "time"
 // TickMsg indicates it's time to refresh the UI.
 type TickMsg time.Time
 
+// CaptureDoneMsg indicates the capture source has stopped producing packets,
+// e.g. because the capture file was read to the end.
+type CaptureDoneMsg struct{}
+
+// CaptureErrorMsg carries an error reported by the capture source.
+type CaptureErrorMsg struct {
+	Err error
+}
+
+// FilterAppliedMsg reports the outcome of changing the capture filters.
+type FilterAppliedMsg struct {
+	CaptureFilter string
+	DisplayFilter string
+	Err           error
+}
+
+// pickerTickMsg indicates it's time to refresh the interface rates in the picker.
+type pickerTickMsg time.Time
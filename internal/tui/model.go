@@ -2,27 +2,114 @@ package tui
 
 import (
 	"gonetwatch/internal/analysis"
+	"gonetwatch/internal/capture"
 	"time"
 
 	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
+// Options describes the capture shown by the dashboard.
+type Options struct {
+	Interfaces    []string // Live interface names
+	CaptureFile   string   // Capture file, when reading a recording
+	MITMTarget    string
+	CaptureFilter string
+	DisplayFilter string
+	// SetFilters applies new filters to the running capture.
+	// It is nil when the filters cannot be changed, e.g. when reading a file.
+	SetFilters func(captureFilter, displayFilter string) error
+	// CaptureStats reports the capture backend's counters, including drops.
+	// It may be nil.
+	CaptureStats func() capture.Stats
+	// Sampling is the capture's sampling; when enabled all figures are estimates.
+	Sampling capture.Sampling
+}
+
+// filterKind identifies the filter being edited.
+type filterKind int
+
+const (
+	editNone filterKind = iota
+	editCapture
+	editDisplay
+)
+
+// viewKind identifies the view shown below the header.
+type viewKind int
+
+const (
+	viewOverview viewKind = iota
+	viewDNS
+	viewTLS
+	viewHTTP
+	viewTCP
+	viewICMP
+	viewQUIC
+)
+
+// viewNames are the tab titles, indexed by viewKind. View n is selected with key n+1.
+var viewNames = []string{"Overview", "DNS", "TLS", "HTTP", "TCP Health", "ICMP", "QUIC"}
+
+// interfaceStat holds the figures shown for one interface or segment in a breakdown panel.
+type interfaceStat struct {
+	name    string
+	bps     float64
+	pps     float64
+	packets int64
+	bytes   int64
+}
+
 type AnalysisModel struct {
-	stats         *analysis.TrafficStats
-	bps           float64
-	pps           float64
-	topTalkers    []analysis.IPStat
-	protocols     []analysis.ProtocolStat
-	table         table.Model
-	interfaceName string
-	mitmTarget    string
+	stats        *analysis.TrafficStats // Combined stats of all interfaces
+	view         viewKind
+	viewIface    string // Interface being viewed, empty for all combined
+	ifaceStats   []interfaceStat
+	viewSegment  string // VLAN/tunnel segment being viewed, empty for all combined
+	segStats     []interfaceStat
+	addressing   analysis.Addressing // Addresses of tunneled packets shown in Top Talkers
+	hasTunnels   bool
+	bps          float64
+	pps          float64
+	topTalkers   []analysis.IPStat
+	protocols    []analysis.ProtocolStat
+	families     []analysis.FamilyStat
+	links        []analysis.LinkStat
+	fieldNames   []string
+	fieldValues  map[string][]analysis.FieldValueStat
+	dns          analysis.DNSReport
+	tls          analysis.TLSReport
+	http         analysis.HTTPReport
+	tcp          analysis.TCPReport
+	icmp         analysis.ICMPReport
+	quic         analysis.QUICReport
+	names        map[string]analysis.DeviceName // Passively learned host names of the addresses on screen
+	table        table.Model
+	opts         Options
+	filterInput  textinput.Model
+	editing      filterKind
+	totalPackets int64
+	totalBytes   int64
+	firstSeen    time.Time
+	lastSeen     time.Time
+	capStats     capture.Stats
+	done         bool
+	errLog       []string // Most recent capture errors, oldest first
 }
 
-func NewAnalysisModel(stats *analysis.TrafficStats, iface string, mitmTarget string) AnalysisModel {
+// maxErrLog is the number of capture errors kept on screen.
+const maxErrLog = 3
+
+// NewAnalysisModel creates the dashboard model. Exactly one of opts.Interfaces
+// or opts.CaptureFile is expected to be set, depending on whether we capture
+// live or read a recorded file.
+func NewAnalysisModel(stats *analysis.TrafficStats, opts Options) AnalysisModel {
 	columns := []table.Column{
-		{Title: "Source IP", Width: 20},
+		{Title: "Source IP", Width: 39}, // Fits a full-length IPv6 address
+		{Title: "Name", Width: 24},
+		{Title: "MAC", Width: 17},
 		{Title: "Bytes", Width: 15},
 	}
 
@@ -44,11 +131,15 @@ func NewAnalysisModel(stats *analysis.TrafficStats, iface string, mitmTarget str
 		Bold(false)
 	t.SetStyles(s)
 
+	ti := textinput.New()
+	ti.CharLimit = 512
+	ti.Width = 60
+
 	return AnalysisModel{
-		stats:         stats,
-		interfaceName: iface,
-		table:         t,
-		mitmTarget:    mitmTarget,
+		stats:       stats,
+		table:       t,
+		opts:        opts,
+		filterInput: ti,
 	}
 }
 
@@ -61,4 +152,3 @@ func tickCmd() tea.Cmd {
 		return TickMsg(t)
 	})
 }
-
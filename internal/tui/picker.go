@@ -0,0 +1,150 @@
+package tui
+
+import (
+	"fmt"
+	"gonetwatch/internal/ifaces"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	cursorStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("229")).
+			Background(lipgloss.Color("57"))
+
+	dimStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("240"))
+)
+
+// PickerModel lets the operator choose the interfaces to capture from,
+// with each interface's addresses, link state and current packet rate.
+type PickerModel struct {
+	ifaces   []ifaces.Interface
+	rates    map[string]float64
+	sampler  *ifaces.RateSampler
+	cursor   int
+	selected map[string]bool
+	chosen   []string
+}
+
+// NewPickerModel creates a picker over the given interfaces.
+func NewPickerModel(list []ifaces.Interface) PickerModel {
+	m := PickerModel{
+		ifaces:   list,
+		sampler:  ifaces.NewRateSampler(),
+		selected: make(map[string]bool),
+	}
+	// Take the baseline now so the first tick already shows rates
+	m.rates = m.sampler.Sample(m.names())
+	return m
+}
+
+// RunPicker shows the picker and returns the chosen interface names,
+// or nil if the operator quit without choosing.
+func RunPicker(list []ifaces.Interface) ([]string, error) {
+	final, err := tea.NewProgram(NewPickerModel(list), tea.WithAltScreen()).Run()
+	if err != nil {
+		return nil, err
+	}
+	return final.(PickerModel).chosen, nil
+}
+
+func (m PickerModel) Init() tea.Cmd {
+	return pickerTickCmd()
+}
+
+func pickerTickCmd() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return pickerTickMsg(t)
+	})
+}
+
+func (m PickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "esc", "ctrl+c":
+			return m, tea.Quit
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.ifaces)-1 {
+				m.cursor++
+			}
+		case " ":
+			if len(m.ifaces) > 0 {
+				name := m.ifaces[m.cursor].Name
+				m.selected[name] = !m.selected[name]
+			}
+		case "enter":
+			// Marked interfaces win; otherwise the one under the cursor
+			for _, iface := range m.ifaces {
+				if m.selected[iface.Name] {
+					m.chosen = append(m.chosen, iface.Name)
+				}
+			}
+			if len(m.chosen) == 0 && len(m.ifaces) > 0 {
+				m.chosen = []string{m.ifaces[m.cursor].Name}
+			}
+			return m, tea.Quit
+		}
+
+	case pickerTickMsg:
+		m.rates = m.sampler.Sample(m.names())
+		return m, pickerTickCmd()
+	}
+	return m, nil
+}
+
+func (m PickerModel) View() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("GoNetWatch - Choose the interfaces to monitor") + "\n\n")
+
+	b.WriteString(dimStyle.Render(fmt.Sprintf("      %-16s %-10s %12s  %s", "Interface", "State", "Packets/s", "Addresses")) + "\n")
+	for i, iface := range m.ifaces {
+		mark := "[ ]"
+		if m.selected[iface.Name] {
+			mark = "[x]"
+		}
+		rate := "-"
+		if r, ok := m.rates[iface.Name]; ok {
+			rate = fmt.Sprintf("%.1f", r)
+		}
+		row := fmt.Sprintf("  %s %-16s %-10s %12s  %s", mark, iface.Name, iface.State, rate, describe(iface))
+		if i == m.cursor {
+			row = cursorStyle.Render(row)
+		}
+		b.WriteString(row + "\n")
+	}
+	if len(m.ifaces) == 0 {
+		b.WriteString("No capture interfaces found.\n")
+	}
+
+	b.WriteString("\n↑/↓: move, space: mark, enter: monitor, q: quit.")
+	return b.String()
+}
+
+// names returns the names of all listed interfaces.
+func (m PickerModel) names() []string {
+	names := make([]string, len(m.ifaces))
+	for i, iface := range m.ifaces {
+		names[i] = iface.Name
+	}
+	return names
+}
+
+// describe returns the addresses of an interface, or its description when it has none.
+func describe(iface ifaces.Interface) string {
+	if len(iface.Addresses) > 0 {
+		return strings.Join(iface.Addresses, ", ")
+	}
+	if iface.Description != "" {
+		return "(" + iface.Description + ")"
+	}
+	return ""
+}
@@ -0,0 +1,38 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// quicView renders the QUIC connection counts, versions and destinations.
+func (m AnalysisModel) quicView() string {
+	q := m.quic
+	if q.Connections == 0 {
+		return infoStyle.Render("QUIC:\nWaiting for QUIC traffic...")
+	}
+
+	summary := fmt.Sprintf("Connections: %d\nPackets: %d\nBytes: %d\nMigrated: %d\nHTTP/3: %d",
+		q.Connections, q.Packets, q.Bytes, q.Migrated, q.HTTP3)
+	summaryBox := infoStyle.Render("QUIC:\n" + summary)
+
+	var versionStrs []string
+	for _, v := range q.Versions {
+		versionStrs = append(versionStrs, fmt.Sprintf("%-24s %8d conn", truncateText(v.Version, 24), v.Connections))
+	}
+	versionBox := infoStyle.Render("Versions:\n" + orWaiting(versionStrs))
+
+	var destStrs []string
+	for _, d := range q.Destinations {
+		line := fmt.Sprintf("%-40s %12d B %6d conn", truncateText(d.ServerName, 40), d.Bytes, d.Connections)
+		if d.HTTP3 > 0 {
+			line += fmt.Sprintf("  %d h3", d.HTTP3)
+		}
+		destStrs = append(destStrs, line)
+	}
+	destBox := infoStyle.Render("Top QUIC Destinations:\n" + orWaiting(destStrs))
+
+	row := lipgloss.JoinHorizontal(lipgloss.Top, summaryBox, versionBox)
+	return lipgloss.JoinVertical(lipgloss.Left, row, destBox)
+}
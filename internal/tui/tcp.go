@@ -0,0 +1,72 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// notEstimable replaces the figures that need several packets of a connection
+// while the capture is sampled; sequenceNotEstimable also covers the
+// retransmissions and duplicate ACKs the native backend flags from samples.
+const (
+	notEstimable         = "Refusals and handshake RTT:\nnot estimable while sampling"
+	sequenceNotEstimable = "Retransmissions, duplicate ACKs,\nrefusals and handshake RTT:\nnot estimable while sampling"
+)
+
+// tcpView renders the TCP health panels, worst offenders first.
+func (m AnalysisModel) tcpView() string {
+	t := m.tcp
+	if t.Segments == 0 {
+		return infoStyle.Render("TCP Health:\nWaiting for TCP traffic...")
+	}
+
+	summary := fmt.Sprintf("Segments: %d\nRetransmissions: %d (%.2f%%)\nDuplicate ACKs: %d\nZero windows: %d\nResets: %d\nRefused: %d\nHandshakes: %d, avg RTT %s",
+		t.Segments, t.Retransmissions, t.RetransmissionRate, t.DuplicateAcks, t.ZeroWindows,
+		t.Resets, t.Refused, t.Handshakes, formatLatency(t.AvgHandshakeRTT))
+	switch {
+	case t.SequenceOmitted:
+		summary = fmt.Sprintf("Segments: %d\nZero windows: %d\nResets: %d\n%s",
+			t.Segments, t.ZeroWindows, t.Resets, sequenceNotEstimable)
+	case t.Sampled:
+		summary = fmt.Sprintf("Segments: %d\nRetransmissions: %d (%.2f%%)\nDuplicate ACKs: %d\nZero windows: %d\nResets: %d\n%s",
+			t.Segments, t.Retransmissions, t.RetransmissionRate, t.DuplicateAcks, t.ZeroWindows, t.Resets, notEstimable)
+	}
+	summaryBox := infoStyle.Render("TCP Health:\n" + summary)
+
+	var serviceStrs []string
+	for _, svc := range t.Services {
+		line := fmt.Sprintf("%-16s avg %s, max %s, %d hs",
+			fmt.Sprintf("%s (%d)", svc.Service, svc.Port), formatLatency(svc.AvgRTT), formatLatency(svc.MaxRTT), svc.Handshakes)
+		if svc.Refused > 0 {
+			line += fmt.Sprintf(", %d refused", svc.Refused)
+		}
+		serviceStrs = append(serviceStrs, line)
+	}
+	if t.Sampled {
+		serviceStrs = []string{notEstimable}
+	}
+	serviceBox := infoStyle.Render("Slowest Handshakes by Service:\n" + orWaiting(serviceStrs))
+
+	header := fmt.Sprintf("%-39s %8s %7s %6s %6s %5s %5s %9s", "Host", "Retrans", "Rate", "DupACK", "ZeroW", "RST", "Refsd", "Avg RTT")
+	hostStrs := []string{header}
+	for _, h := range t.Hosts {
+		rtt := "-"
+		if h.Handshakes > 0 {
+			rtt = formatLatency(h.AvgRTT)
+		}
+		hostStrs = append(hostStrs, fmt.Sprintf("%-39s %8d %6.2f%% %6d %6d %5d %5d %9s",
+			m.hostLabel(h.IP, 39), h.Retransmissions, h.RetransmissionRate, h.DuplicateAcks, h.ZeroWindows, h.Resets, h.Refused, rtt))
+	}
+	title := "Worst Hosts:\n"
+	switch {
+	case t.SequenceOmitted:
+		title = "Worst Hosts (zero windows and resets only while sampling):\n"
+	case t.Sampled:
+		title = "Worst Hosts (no refusals or RTT while sampling):\n"
+	}
+	hostBox := infoStyle.Render(title + orWaiting(hostStrs))
+
+	row1 := lipgloss.JoinHorizontal(lipgloss.Top, summaryBox, serviceBox)
+	return lipgloss.JoinVertical(lipgloss.Left, row1, hostBox)
+}
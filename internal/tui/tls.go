@@ -0,0 +1,41 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// tlsView renders the top TLS destinations and client fingerprints.
+func (m AnalysisModel) tlsView() string {
+	t := m.tls
+	if t.Connections == 0 {
+		return infoStyle.Render("TLS:\nWaiting for TLS handshakes...")
+	}
+
+	var destStrs []string
+	for _, d := range t.Destinations {
+		line := fmt.Sprintf("%-40s %12d B %6d conn", truncateText(d.ServerName, 40), d.Bytes, d.Connections)
+		if len(d.Versions) > 0 {
+			line += "  " + strings.Join(d.Versions, "/")
+		}
+		if len(d.ALPN) > 0 {
+			line += "  " + strings.Join(d.ALPN, ",")
+		}
+		destStrs = append(destStrs, line)
+	}
+	destBox := infoStyle.Render(fmt.Sprintf("Top TLS Destinations (%d connections):\n", t.Connections) + orWaiting(destStrs))
+
+	var fpStrs []string
+	for _, fp := range t.Fingerprints {
+		id := "JA3 " + fp.JA3
+		if fp.JA4 != "" {
+			id = "JA4 " + fp.JA4
+		}
+		fpStrs = append(fpStrs, fmt.Sprintf("%-40s %6d conn  %s", id, fp.Connections, strings.Join(fp.ServerNames, ", ")))
+	}
+	fpBox := infoStyle.Render("Client Fingerprints:\n" + orWaiting(fpStrs))
+
+	return lipgloss.JoinVertical(lipgloss.Left, destBox, fpBox)
+}
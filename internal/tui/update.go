@@ -2,6 +2,10 @@ package tui
 
 import (
 	"fmt"
+	"gonetwatch/internal/analysis"
+	"slices"
+	"strconv"
+	"time"
 
 	"github.com/charmbracelet/bubbles/table"
 	tea "github.com/charmbracelet/bubbletea"
@@ -12,23 +16,127 @@ func (m AnalysisModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.editing != editNone {
+			return m.updateFilterInput(msg)
+		}
+
 		switch msg.String() {
 		case "q", "ctrl+c":
 			return m, tea.Quit
+		case "f":
+			return m.startEditing(editCapture, m.opts.CaptureFilter)
+		case "/":
+			return m.startEditing(editDisplay, m.opts.DisplayFilter)
+		case "i":
+			m.viewIface = m.nextInterface()
+			m.viewSegment = ""
+			return m, nil
+		case "g":
+			m.viewSegment = m.nextSegment()
+			m.viewIface = ""
+			return m, nil
+		case "a":
+			if m.addressing == analysis.AddressInner {
+				m.addressing = analysis.AddressOuter
+			} else if m.hasTunnels {
+				m.addressing = analysis.AddressInner
+			}
+			return m, nil
 		}
+		if n, err := strconv.Atoi(msg.String()); err == nil && n >= 1 && n <= len(viewNames) {
+			m.view = viewKind(n - 1)
+			return m, nil
+		}
+
+	case FilterAppliedMsg:
+		if msg.Err != nil {
+			m.logError(msg.Err)
+			return m, nil
+		}
+		m.opts.CaptureFilter = msg.CaptureFilter
+		m.opts.DisplayFilter = msg.DisplayFilter
+		return m, nil
+
+	case CaptureDoneMsg:
+		m.done = true
+		// Keep the last measured rates on screen; the window is empty from now on.
+		return m, nil
+
+	case CaptureErrorMsg:
+		m.logError(msg.Err)
+		return m, nil
 
 	case TickMsg:
 		// Fetch stats
-		bps, pps := m.stats.GetRates()
-		m.bps = bps
-		m.pps = pps
-		m.topTalkers = m.stats.GetTopTalkers(10)
-		m.protocols = m.stats.GetProtocolStats()
+		// Rates are read for every interface on each tick so their windows stay aligned
+		if !m.done {
+			m.bps, m.pps = m.stats.GetRates()
+			m.ifaceStats = m.ifaceStats[:0]
+			for _, name := range m.stats.GetInterfaces() {
+				child := m.stats.ForInterface(name)
+				st := interfaceStat{name: name}
+				st.bps, st.pps = child.GetRates()
+				st.packets, st.bytes = child.GetTotals()
+				m.ifaceStats = append(m.ifaceStats, st)
+				if name == m.viewIface {
+					m.bps, m.pps = st.bps, st.pps
+				}
+			}
+			m.segStats = m.segStats[:0]
+			for _, name := range m.stats.GetSegments() {
+				child := m.stats.ForSegment(name)
+				st := interfaceStat{name: name}
+				st.bps, st.pps = child.GetRates()
+				st.packets, st.bytes = child.GetTotals()
+				m.segStats = append(m.segStats, st)
+				if name == m.viewSegment {
+					m.bps, m.pps = st.bps, st.pps
+				}
+			}
+		}
+		m.hasTunnels = m.stats.HasTunnels()
+
+		if m.opts.CaptureStats != nil {
+			m.capStats = m.opts.CaptureStats()
+		}
+
+		view := m.currentStats()
+		m.totalPackets, m.totalBytes = view.GetTotals()
+		m.firstSeen, m.lastSeen = view.GetTimeSpan()
+		m.topTalkers = view.GetTopTalkersBy(m.addressing, 10)
+		m.protocols = view.GetProtocolStats()
+		m.families = view.GetFamilyStats()
+		m.links = view.GetLinkStats()
+		m.fieldNames = view.GetFieldNames()
+		m.fieldValues = make(map[string][]analysis.FieldValueStat, len(m.fieldNames))
+		for _, name := range m.fieldNames {
+			m.fieldValues[name] = view.GetTopFieldValues(name, 3)
+		}
+
+		// Protocol views are only refreshed while shown
+		switch m.view {
+		case viewDNS:
+			m.dns = view.GetDNSReport(10)
+		case viewTLS:
+			m.tls = view.GetTLSReport(10)
+		case viewHTTP:
+			m.http = view.GetHTTPReport(10)
+		case viewTCP:
+			m.tcp = view.GetTCPReport(10)
+		case viewICMP:
+			m.icmp = view.GetICMPReport(10)
+		case viewQUIC:
+			m.quic = view.GetQUICReport(10)
+		}
+
+		// Names are looked up for the addresses on screen only
+		m.names = m.stats.GetNamesFor(m.shownAddresses())
 
 		// Update table
 		rows := make([]table.Row, len(m.topTalkers))
 		for i, stat := range m.topTalkers {
-			rows[i] = table.Row{stat.IP, fmt.Sprintf("%d", stat.Bytes)}
+			d := m.names[stat.IP]
+			rows[i] = table.Row{stat.IP, d.Name, d.MAC, fmt.Sprintf("%d", stat.Bytes)}
 		}
 		m.table.SetRows(rows)
 
@@ -39,3 +147,127 @@ func (m AnalysisModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// shownAddresses returns the addresses the top talkers table and the current
+// view label with names.
+func (m AnalysisModel) shownAddresses() []string {
+	var ips []string
+	for _, t := range m.topTalkers {
+		ips = append(ips, t.IP)
+	}
+	switch m.view {
+	case viewDNS:
+		for _, r := range m.dns.Resolvers {
+			ips = append(ips, r.IP)
+		}
+		for _, c := range m.dns.Clients {
+			ips = append(ips, c.IP)
+		}
+	case viewTCP:
+		for _, h := range m.tcp.Hosts {
+			ips = append(ips, h.IP)
+		}
+	case viewICMP:
+		for _, h := range slices.Concat(m.icmp.Sources, m.icmp.Destinations) {
+			ips = append(ips, h.IP)
+		}
+		for _, p := range m.icmp.Pings {
+			ips = append(ips, p.Target)
+		}
+	}
+	return ips
+}
+
+// logError appends an error to the on-screen error log.
+func (m *AnalysisModel) logError(err error) {
+	entry := time.Now().Format("15:04:05") + " " + err.Error()
+	m.errLog = append(m.errLog, entry)
+	if len(m.errLog) > maxErrLog {
+		m.errLog = m.errLog[len(m.errLog)-maxErrLog:]
+	}
+}
+
+// startEditing opens the filter input, if the capture supports changing filters.
+func (m AnalysisModel) startEditing(kind filterKind, current string) (tea.Model, tea.Cmd) {
+	if m.opts.SetFilters == nil || m.done {
+		return m, nil
+	}
+	m.editing = kind
+	m.filterInput.SetValue(current)
+	m.filterInput.CursorEnd()
+	return m, m.filterInput.Focus()
+}
+
+// updateFilterInput handles keys while a filter is being edited.
+// Enter applies the filter in the background, Esc cancels.
+func (m AnalysisModel) updateFilterInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.editing = editNone
+		m.filterInput.Blur()
+		return m, nil
+	case "enter":
+		captureFilter, displayFilter := m.opts.CaptureFilter, m.opts.DisplayFilter
+		if m.editing == editCapture {
+			captureFilter = m.filterInput.Value()
+		} else {
+			displayFilter = m.filterInput.Value()
+		}
+		m.editing = editNone
+		m.filterInput.Blur()
+
+		setFilters := m.opts.SetFilters
+		return m, func() tea.Msg {
+			err := setFilters(captureFilter, displayFilter)
+			return FilterAppliedMsg{CaptureFilter: captureFilter, DisplayFilter: displayFilter, Err: err}
+		}
+	}
+
+	var cmd tea.Cmd
+	m.filterInput, cmd = m.filterInput.Update(msg)
+	return m, cmd
+}
+
+// currentStats returns the stats of the interface or segment being viewed,
+// or the combined stats.
+func (m AnalysisModel) currentStats() *analysis.TrafficStats {
+	if m.viewSegment != "" {
+		if child := m.stats.ForSegment(m.viewSegment); child != nil {
+			return child
+		}
+	}
+	if m.viewIface != "" {
+		if child := m.stats.ForInterface(m.viewIface); child != nil {
+			return child
+		}
+	}
+	return m.stats
+}
+
+// nextInterface cycles the view: all combined, then each interface in turn.
+func (m AnalysisModel) nextInterface() string {
+	return nextName(m.stats.GetInterfaces(), m.viewIface)
+}
+
+// nextSegment cycles the view: all combined, then each segment in turn.
+func (m AnalysisModel) nextSegment() string {
+	return nextName(m.stats.GetSegments(), m.viewSegment)
+}
+
+// nextName returns the name following current, the first name if current
+// is empty, and empty after the last name.
+func nextName(names []string, current string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	if current == "" {
+		return names[0]
+	}
+	for i, name := range names {
+		if name == current && i+1 < len(names) {
+			return names[i+1]
+		}
+	}
+	return ""
+}
@@ -3,6 +3,7 @@ package tui
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 )
@@ -19,21 +20,124 @@ var (
 			Border(lipgloss.RoundedBorder()).
 			Padding(0, 1).
 			Margin(0, 1)
+
+	errorStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FF5F87")).
+			Bold(true)
 )
 
 func (m AnalysisModel) View() string {
-	headerText := fmt.Sprintf("GoNetWatch - Monitoring: %s", m.interfaceName)
-	if m.mitmTarget != "" {
-		headerText += fmt.Sprintf(" [MITM Target: %s]", m.mitmTarget)
+	headerText := fmt.Sprintf("GoNetWatch - Monitoring: %s", strings.Join(m.opts.Interfaces, ", "))
+	if m.opts.CaptureFile != "" {
+		headerText = fmt.Sprintf("GoNetWatch - Reading: %s", m.opts.CaptureFile)
+	}
+	if m.opts.MITMTarget != "" {
+		headerText += fmt.Sprintf(" [MITM Target: %s]", m.opts.MITMTarget)
+	}
+	if m.opts.Sampling.Enabled() {
+		headerText += fmt.Sprintf(" [Sampled %s - estimates]", m.opts.Sampling)
+	}
+	if m.viewSegment != "" {
+		headerText += fmt.Sprintf(" [View: %s]", m.viewSegment)
+	} else if m.viewIface != "" {
+		headerText += fmt.Sprintf(" [View: %s]", m.viewIface)
+	} else if len(m.ifaceStats) > 1 {
+		headerText += " [View: all interfaces]"
+	}
+	if m.done {
+		if m.opts.CaptureFile != "" {
+			headerText += " [End of file - final stats]"
+		} else {
+			headerText += " [Capture stopped]"
+		}
 	}
 	title := titleStyle.Render(headerText)
 
+	// Filters
+	filterLine := fmt.Sprintf("Capture filter: %s | Display filter: %s",
+		orNone(m.opts.CaptureFilter), orNone(m.opts.DisplayFilter))
+	switch m.editing {
+	case editCapture:
+		filterLine = "Capture filter: " + m.filterInput.View()
+	case editDisplay:
+		filterLine = "Display filter: " + m.filterInput.View()
+	}
+	title = lipgloss.JoinVertical(lipgloss.Left, title, filterLine, m.captureLine())
+
+	var content string
+	switch {
+	case m.viewSegment != "" && m.view != viewOverview:
+		// Segments only keep totals, talkers and protocols
+		content = infoStyle.Render("The protocol views cover all segments combined.\nPress g to return to all traffic.")
+	case m.view == viewDNS:
+		content = m.dnsView()
+	case m.view == viewTLS:
+		content = m.tlsView()
+	case m.view == viewHTTP:
+		content = m.httpView()
+	case m.view == viewTCP:
+		content = m.tcpView()
+	case m.view == viewICMP:
+		content = m.icmpView()
+	case m.view == viewQUIC:
+		content = m.quicView()
+	default:
+		content = m.overviewView()
+	}
+	body := lipgloss.JoinVertical(lipgloss.Left, title, m.tabBar(), content)
+
+	for _, entry := range m.errLog {
+		body += "\n" + errorStyle.Render(entry)
+	}
+
+	help := "Press q to quit."
+	if m.editing != editNone {
+		help = "Enter to apply, Esc to cancel."
+	} else if m.opts.SetFilters != nil && !m.done {
+		help = "f: capture filter, /: display filter, q: quit."
+	}
+	if len(m.ifaceStats) > 1 && m.editing == editNone {
+		help = "i: switch interface, " + help
+	}
+	if len(m.segStats) > 0 && m.editing == editNone {
+		help = "g: switch segment, " + help
+	}
+	if m.hasTunnels && m.editing == editNone {
+		help = "a: outer/inner addresses, " + help
+	}
+	if m.editing == editNone {
+		help = fmt.Sprintf("1-%d: views, ", len(viewNames)) + help
+	}
+	return body + "\n" + help
+}
+
+// overviewView renders the traffic overview panels.
+func (m AnalysisModel) overviewView() string {
 	// QoS Panel
-	qos := fmt.Sprintf("Bandwidth: %s\nPacket Rate: %.2f PPS", formatBps(m.bps), m.pps)
+	qos := fmt.Sprintf("Bandwidth: %s\nPacket Rate: %.2f PPS\nTotal: %d packets, %d bytes",
+		formatBps(m.bps), m.pps, m.totalPackets, m.totalBytes)
+	if span := m.lastSeen.Sub(m.firstSeen); span > 0 {
+		qos += fmt.Sprintf("\nSpan: %s", span.Round(time.Millisecond))
+		if m.done {
+			// Average over the whole capture, measured on packet time
+			qos += fmt.Sprintf(" (avg %s)", formatBps(float64(m.totalBytes)*8/span.Seconds()))
+		}
+	}
+	if len(m.families) > 0 {
+		var famStrs []string
+		for _, f := range m.families {
+			famStrs = append(famStrs, fmt.Sprintf("%s %.1f%%", f.Family, percent(f.Bytes, m.totalBytes)))
+		}
+		qos += "\n" + strings.Join(famStrs, " / ")
+	}
 	qosBox := infoStyle.Render(qos)
 
 	// Top Talkers
-	ttBox := infoStyle.Render("Top Talkers\n" + m.table.View())
+	ttTitle := "Top Talkers"
+	if m.hasTunnels {
+		ttTitle += fmt.Sprintf(" (%s addresses of tunneled traffic)", m.addressing)
+	}
+	ttBox := infoStyle.Render(ttTitle + "\n" + m.table.View())
 
 	// Protocols
 	var protoStrs []string
@@ -44,18 +148,130 @@ func (m AnalysisModel) View() string {
 
 	for i := 0; i < limit; i++ {
 		p := m.protocols[i]
-		protoStrs = append(protoStrs, fmt.Sprintf("%s: %d", p.Protocol, p.Count))
+		protoStrs = append(protoStrs, fmt.Sprintf("%s: %d pkts, %d B", p.Protocol, p.Count, p.Bytes))
 	}
 	if len(protoStrs) == 0 {
 		protoStrs = append(protoStrs, "Waiting for data...")
 	}
 	protoBox := infoStyle.Render("Protocols:\n" + strings.Join(protoStrs, "\n"))
 
+	// Link layer
+	var linkStrs []string
+	for i, l := range m.links {
+		if i == 5 {
+			break
+		}
+		linkStrs = append(linkStrs, fmt.Sprintf("%s: %d pkts, %d B", l.Name, l.Packets, l.Bytes))
+	}
+	if len(linkStrs) == 0 {
+		linkStrs = append(linkStrs, "Waiting for data...")
+	}
+	linkBox := infoStyle.Render("Link Layer:\n" + strings.Join(linkStrs, "\n"))
+
 	// Layout
-	row1 := lipgloss.JoinHorizontal(lipgloss.Top, qosBox, protoBox)
-	body := lipgloss.JoinVertical(lipgloss.Left, title, row1, ttBox)
+	row1 := lipgloss.JoinHorizontal(lipgloss.Top, qosBox, protoBox, linkBox)
+	body := lipgloss.JoinVertical(lipgloss.Left, row1, ttBox)
 
-	return body + "\nPress q to quit."
+	// Per-interface breakdown
+	if len(m.ifaceStats) > 1 {
+		var ifaceStrs []string
+		for _, st := range m.ifaceStats {
+			marker := "  "
+			if st.name == m.viewIface {
+				marker = "> "
+			}
+			ifaceStrs = append(ifaceStrs, fmt.Sprintf("%s%s: %s, %.2f PPS, %d packets, %d bytes",
+				marker, st.name, formatBps(st.bps), st.pps, st.packets, st.bytes))
+		}
+		body = lipgloss.JoinVertical(lipgloss.Left, body, infoStyle.Render("Interfaces:\n"+strings.Join(ifaceStrs, "\n")))
+	}
+
+	// Per-segment breakdown
+	if len(m.segStats) > 0 {
+		var segStrs []string
+		for _, st := range m.segStats {
+			marker := "  "
+			if st.name == m.viewSegment {
+				marker = "> "
+			}
+			segStrs = append(segStrs, fmt.Sprintf("%s%s: %s, %.2f PPS, %d packets, %d bytes",
+				marker, st.name, formatBps(st.bps), st.pps, st.packets, st.bytes))
+		}
+		body = lipgloss.JoinVertical(lipgloss.Left, body, infoStyle.Render("VLANs / Tunnels:\n"+strings.Join(segStrs, "\n")))
+	}
+
+	// Extra fields requested by the user
+	if len(m.fieldNames) > 0 {
+		var fieldStrs []string
+		for _, name := range m.fieldNames {
+			var values []string
+			for _, v := range m.fieldValues[name] {
+				values = append(values, fmt.Sprintf("%s (%d)", v.Value, v.Packets))
+			}
+			fieldStrs = append(fieldStrs, fmt.Sprintf("%s: %s", name, strings.Join(values, ", ")))
+		}
+		body = lipgloss.JoinVertical(lipgloss.Left, body, infoStyle.Render("Fields:\n"+strings.Join(fieldStrs, "\n")))
+	}
+
+	return body
+}
+
+// tabBar lists the views, highlighting the current one.
+func (m AnalysisModel) tabBar() string {
+	var tabs []string
+	for i, name := range viewNames {
+		tab := fmt.Sprintf("[%d] %s", i+1, name)
+		if viewKind(i) == m.view {
+			tab = titleStyle.Render(tab)
+		}
+		tabs = append(tabs, tab)
+	}
+	return strings.Join(tabs, " ")
+}
+
+// captureLine summarizes the backend counters. It is highlighted once packets
+// have been lost, since the figures above then undercount the traffic.
+func (m AnalysisModel) captureLine() string {
+	cs := m.capStats
+	kernel := fmt.Sprintf("%d kernel", cs.KernelDrops)
+	if cs.KernelDropsPending {
+		kernel += " (+ buffer drops on exit)"
+	}
+	line := fmt.Sprintf("Capture: drops %s, %d pipeline | %d malformed, %d skipped | %d restarts",
+		kernel, cs.PipelineDrops, cs.Malformed, cs.Skipped, cs.Restarts)
+	if cs.SampledOut > 0 {
+		line += fmt.Sprintf(" | %d sampled out", cs.SampledOut)
+	}
+	if cs.Lost() > 0 {
+		line = errorStyle.Render(line + " - stats are incomplete")
+	}
+	if cs.RingFile != "" {
+		line += "\nWriting: " + cs.RingFile
+	}
+	return line
+}
+
+// hostLabel returns an address followed by the name learned for it, shortened
+// to n characters.
+func (m AnalysisModel) hostLabel(ip string, n int) string {
+	if d := m.names[ip]; d.Name != "" {
+		ip += " (" + d.Name + ")"
+	}
+	return truncateText(ip, n)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+func percent(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
 }
 
 func formatBps(bps float64) string {
@@ -1,38 +1,165 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"gonetwatch/internal/analysis"
+	"gonetwatch/internal/capture"
+	"gonetwatch/internal/export"
+	"gonetwatch/internal/ifaces"
 	"gonetwatch/internal/models"
+	_ "gonetwatch/internal/sniffer" // registers the "native" capture backend
 	"gonetwatch/internal/spoofer"
-	"gonetwatch/internal/tshark"
+	"gonetwatch/internal/tshark" // also registers the "tshark" capture backend
 	"gonetwatch/internal/tui"
 	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func main() {
-	interfaceName := flag.String("i", "", "Network interface to capture from (e.g., eth0, wlan0)")
+	interfaceName := flag.String("i", "", "Network interface(s) to capture from, comma separated (e.g., eth0 or eth0,wlan0)")
+	readFile := flag.String("r", "", "Read packets from a .pcap/.pcapng file instead of a live interface")
+	backend := flag.String("backend", "tshark", "Capture backend to use ("+strings.Join(capture.Backends(), ", ")+")")
+	fieldList := flag.String("fields", "", "Extra tshark fields to extract, comma separated, each as name[:string|int|float|bool] (e.g. dns.qry.name,vlan.id:int)")
+	fieldsFile := flag.String("fields-file", "", "File listing extra tshark fields, one name[:type] per line")
+	userCaptureFilter := flag.String("f", "", "BPF capture filter (e.g. \"tcp port 443\")")
+	displayFilter := flag.String("Y", "", "Wireshark display filter (e.g. \"dns || tls\"), tshark backend only")
+	outputFormat := flag.String("tshark-output", "", "tshark output format ("+strings.Join(tshark.Formats(), ", ")+"); default ek")
+	benchFile := flag.String("bench", "", "Measure how fast each tshark output format is decoded for this capture file, then exit")
+	ringPath := flag.String("w", "", "Also write live packets to a rotating pcapng ring buffer based on this file name")
+	ringSize := flag.Int64("ring-size", 100, "Ring buffer file size in MB before rotating (0 for no limit)")
+	ringFiles := flag.Int("ring-files", 10, "Ring buffer files to keep (0 to keep all)")
+	ringDuration := flag.Duration("ring-duration", 0, "Ring buffer time per file before rotating, e.g. 10m (0 for no limit)")
+	sampleEvery := flag.Int("sample", 0, "Analyze only 1 packet in N and scale the statistics up (0 to analyze all)")
+	sampleProb := flag.Float64("sample-prob", 0, "Analyze each packet with this probability, e.g. 0.01, and scale the statistics up")
+	segments := flag.Bool("segments", false, "Break traffic totals, top talkers and protocols down by VLAN and tunnel network")
+	exportPath := flag.String("export", "", "Write a JSON report of the final statistics to this file on exit")
 	targetIP := flag.String("target", "", "Target IP for MITM (requires -gateway)")
 	gatewayIP := flag.String("gateway", "", "Gateway IP for MITM (requires -target)")
 	flag.Parse()
 
-	if *interfaceName == "" {
-		fmt.Println("Please provide an interface name with -i")
-		fmt.Println("Example: ./gonetwatch -i wlan0")
+	if flag.NArg() > 0 {
+		switch flag.Arg(0) {
+		case "list-interfaces":
+			listInterfaces()
+		default:
+			log.Fatalf("Unknown command %q (available: list-interfaces)", flag.Arg(0))
+		}
+		return
+	}
+
+	if *benchFile != "" {
+		extraFields, err := loadFieldSpecs(*fieldList, *fieldsFile)
+		if err != nil {
+			log.Fatalf("Invalid extra fields: %v", err)
+		}
+		runBenchmark(*benchFile, extraFields)
 		return
 	}
 
+	if *interfaceName == "" && *readFile == "" {
+		// Let the operator pick from the interfaces of this host
+		list, err := ifaces.List()
+		if err != nil {
+			log.Fatalf("Failed to list interfaces: %v", err)
+		}
+		chosen, err := tui.RunPicker(list)
+		if err != nil {
+			log.Fatalf("Error running interface picker: %v", err)
+		}
+		if len(chosen) == 0 {
+			fmt.Println("Please provide an interface name with -i or a capture file with -r")
+			fmt.Println("Example: ./gonetwatch -i wlan0")
+			fmt.Println("Example: ./gonetwatch -r capture.pcapng")
+			fmt.Println("Run ./gonetwatch list-interfaces to see the available interfaces")
+			return
+		}
+		*interfaceName = strings.Join(chosen, ",")
+	}
+	if *interfaceName != "" && *readFile != "" {
+		log.Fatal("-i and -r cannot be used together")
+	}
+	if *readFile != "" && (*targetIP != "" || *gatewayIP != "") {
+		log.Fatal("MITM mode requires a live interface (-i)")
+	}
+	if *readFile != "" && *ringPath != "" {
+		log.Fatal("-w records live captures and cannot be used with -r")
+	}
+
+	extraFields, err := loadFieldSpecs(*fieldList, *fieldsFile)
+	if err != nil {
+		log.Fatalf("Invalid extra fields: %v", err)
+	}
+
+	var interfaces []string
+	for _, name := range strings.Split(*interfaceName, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			interfaces = append(interfaces, name)
+		}
+	}
+
+	mitmMode := *targetIP != "" && *gatewayIP != ""
+	if !mitmMode && (*targetIP != "" || *gatewayIP != "") {
+		log.Fatal("Both -target and -gateway must be specified for MITM mode")
+	}
+	if mitmMode && len(interfaces) != 1 {
+		log.Fatal("MITM mode requires exactly one interface")
+	}
+
+	// In MITM mode we want to ignore packets originating from our own MAC
+	// (re-transmissions) to avoid double counting
+	var mitmFilter string
+	if mitmMode {
+		iface, err := net.InterfaceByName(interfaces[0])
+		if err != nil {
+			log.Fatalf("Failed to get interface: %v", err)
+		}
+		mitmFilter = fmt.Sprintf("not ether src %s", iface.HardwareAddr.String())
+	}
+	captureFilter := capture.CombineFilters(*userCaptureFilter, mitmFilter)
+
+	sampling := capture.Sampling{Every: *sampleEvery, Probability: *sampleProb}
+
+	// Create the capture source and check filters before touching the network
+	source, err := capture.New(*backend, capture.Config{
+		Interfaces:    interfaces,
+		File:          *readFile,
+		CaptureFilter: captureFilter,
+		DisplayFilter: *displayFilter,
+		ExtraFields:   extraFields,
+		OutputFormat:  *outputFormat,
+		Ring: capture.RingBuffer{
+			Path:        *ringPath,
+			MaxSize:     *ringSize * 1000 * 1000,
+			MaxFiles:    *ringFiles,
+			MaxDuration: *ringDuration,
+		},
+		Sampling: sampling,
+	})
+	if err != nil {
+		log.Fatalf("Error creating capture source: %v", err)
+	}
+	if v, ok := source.(capture.Validator); ok {
+		if err := v.Validate(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	// MITM Setup
-	var captureFilter string
 	var mitmTarget string
-	
-	if *targetIP != "" && *gatewayIP != "" {
+
+	if mitmMode {
 		fmt.Println("Starting MITM setup...")
 		mitmTarget = *targetIP
-		
+
 		// 1. Enable IP Forwarding
 		if err := spoofer.EnableIPForwarding(); err != nil {
 			log.Fatalf("Failed to enable IP forwarding: %v", err)
@@ -43,7 +170,7 @@ func main() {
 		}()
 
 		// 2. Initialize Spoofer Engine
-		engine, err := spoofer.NewEngine(*targetIP, *gatewayIP, *interfaceName)
+		engine, err := spoofer.NewEngine(*targetIP, *gatewayIP, interfaces[0])
 		if err != nil {
 			log.Fatalf("Failed to initialize spoofer: %v", err)
 		}
@@ -57,43 +184,174 @@ func main() {
 			engine.Stop()
 		}()
 
-		// 4. Set Filter to avoid double counting
-		// We want to ignore packets originating from our own MAC (re-transmissions)
-		captureFilter = fmt.Sprintf("not ether src %s", engine.HostMAC.String())
 		fmt.Printf("MITM Active. Filter: %s\n", captureFilter)
-	} else if *targetIP != "" || *gatewayIP != "" {
-		log.Fatal("Both -target and -gateway must be specified for MITM mode")
 	}
 
-	// Create channel for packets
-	packetChan := make(chan models.PacketData, 1000)
+	// Stop the capture (and any tshark process) when we are interrupted or exit
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
 
-	// Start Tshark capture
-	err := tshark.StartCapture(*interfaceName, captureFilter, packetChan)
-	if err != nil {
+	if err := source.Start(ctx); err != nil {
 		log.Fatalf("Error starting capture: %v", err)
 	}
+	defer source.Stop()
 
 	// Initialize analysis engine
 	stats := analysis.NewTrafficStats()
+	if len(interfaces) > 1 {
+		stats.TrackInterfaces()
+	}
+	if *segments {
+		stats.TrackSegments()
+	}
+	stats.SetSampleScale(sampling.Scale())
+
+	// Initialize the TUI
+	// We pass the mitmTarget string to update the UI header
+	opts := tui.Options{
+		Interfaces:    interfaces,
+		CaptureFile:   *readFile,
+		MITMTarget:    mitmTarget,
+		CaptureFilter: *userCaptureFilter,
+		DisplayFilter: *displayFilter,
+		CaptureStats:  source.Stats,
+		Sampling:      sampling,
+	}
+	if r, ok := source.(capture.Refilterer); ok && *readFile == "" {
+		// Filters edited in the TUI keep the MITM exclusion
+		opts.SetFilters = func(userFilter, displayFilter string) error {
+			return r.SetFilters(capture.CombineFilters(userFilter, mitmFilter), displayFilter)
+		}
+	}
+	model := tui.NewAnalysisModel(stats, opts)
+	p := tea.NewProgram(model, tea.WithAltScreen()) // Use AltScreen for full terminal UI
 
 	// Background packet processor
+	// The channel is closed when the source is exhausted (end of file or tshark exit),
+	// at which point the TUI keeps showing the final stats.
 	go func() {
-		for pkt := range packetChan {
+		for pkt := range source.Packets() {
 			stats.ProcessPacket(pkt)
 		}
+		p.Send(tui.CaptureDoneMsg{})
+	}()
+
+	// Leave the TUI when we are asked to terminate
+	go func() {
+		<-ctx.Done()
+		p.Quit()
+	}()
+
+	// Forward capture errors to the TUI instead of writing over the alt-screen
+	go func() {
+		for err := range source.Errors() {
+			p.Send(tui.CaptureErrorMsg{Err: err})
+		}
 	}()
 
-	// Initialize and run the TUI
-	// We pass the mitmTarget string to update the UI header
-	model := tui.NewAnalysisModel(stats, *interfaceName, mitmTarget)
-	p := tea.NewProgram(model, tea.WithAltScreen()) // Use AltScreen for full terminal UI
-	
 	if _, err := p.Run(); err != nil {
 		// TUI exited with error
 		log.Printf("Error running TUI: %v", err)
 		// Defers will run here
 	}
-	
+
+	if *exportPath != "" {
+		// Stop first so the final drop counts are included
+		source.Stop()
+		capStats := source.Stats()
+		name := strings.Join(interfaces, ",")
+		if *readFile != "" {
+			name = *readFile
+		}
+		if err := export.WriteJSON(*exportPath, export.Build(stats, name, capStats)); err != nil {
+			log.Printf("Error exporting report: %v", err)
+		} else {
+			fmt.Printf("Report written to %s\n", *exportPath)
+		}
+	}
+
 	// Normal exit - defers will run
 }
+
+// listInterfaces prints the capture interfaces with their packet rate,
+// sampled over one second.
+func listInterfaces() {
+	list, err := ifaces.List()
+	if err != nil {
+		log.Fatalf("Failed to list interfaces: %v", err)
+	}
+
+	names := make([]string, len(list))
+	for i, iface := range list {
+		names[i] = iface.Name
+	}
+	sampler := ifaces.NewRateSampler()
+	sampler.Sample(names)
+	time.Sleep(time.Second)
+	rates := sampler.Sample(names)
+
+	fmt.Printf("%-16s %-10s %10s  %-17s %s\n", "Interface", "State", "Packets/s", "MAC", "Addresses")
+	for _, iface := range list {
+		rate := "-"
+		if r, ok := rates[iface.Name]; ok {
+			rate = fmt.Sprintf("%.1f", r)
+		}
+		addrs := strings.Join(iface.Addresses, ", ")
+		if iface.Description != "" {
+			addrs = strings.TrimSpace(addrs + " (" + iface.Description + ")")
+		}
+		fmt.Printf("%-16s %-10s %10s  %-17s %s\n", iface.Name, iface.State, rate, orDash(iface.MAC), addrs)
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// runBenchmark prints the decoding throughput of every tshark output format.
+func runBenchmark(path string, extraFields []models.FieldSpec) {
+	results, err := tshark.Benchmark(path, extraFields)
+	if err != nil {
+		log.Fatalf("Benchmark failed: %v", err)
+	}
+
+	fmt.Printf("%-8s %10s %12s %16s\n", "Format", "Packets", "Output", "Decode rate")
+	for _, r := range results {
+		fmt.Printf("%-8s %10d %10.1f MB %12.0f pps\n",
+			r.Format, r.Packets, float64(r.OutputBytes)/1e6, r.PacketsPerSecond())
+	}
+}
+
+// loadFieldSpecs collects extra field specs from the -fields list and the -fields-file.
+// Blank lines and lines starting with # are ignored in the file.
+func loadFieldSpecs(list string, path string) ([]models.FieldSpec, error) {
+	var raw []string
+	if list != "" {
+		raw = append(raw, strings.Split(list, ",")...)
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "#") {
+				raw = append(raw, line)
+			}
+		}
+	}
+
+	specs := make([]models.FieldSpec, 0, len(raw))
+	for _, r := range raw {
+		spec, err := models.ParseFieldSpec(r)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}